@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"rate-limiter/config"
+	"rate-limiter/internal/admin"
+	"rate-limiter/internal/health"
 	"rate-limiter/internal/limiter"
+	"rate-limiter/internal/metrics"
 	"rate-limiter/internal/storage"
+	"rate-limiter/internal/tracing"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -14,20 +24,42 @@ import (
 func main() {
 	config.LoadConfig()
 	config.InitLogger()
+	// Valores inválidos desligariam proteções em silêncio (ex.: LOGIN_FAILURE_WINDOW_SECONDS=0)
+	if err := config.Cfg.Validate(); err != nil {
+		config.Logger.Fatal("Configuração inválida", zap.Error(err))
+	}
 
-	var rateLimiterStorage storage.RateLimiterStorage
-	if config.Cfg.RedisAddr != "" {
-		redisStorage := storage.NewRedisStorage(config.Cfg.RedisAddr, config.Cfg.RedisPassword, config.Cfg.RedisDB)
+	shutdownTracing, err := tracing.Setup(context.Background(), config.Cfg.TracingExporter, config.Cfg.TracingServiceName)
+	if err != nil {
+		config.Logger.Fatal("Erro ao configurar tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
 
-		if err := redisStorage.Ping(); err != nil {
-			config.Logger.Fatal("Erro ao conectar ao Redis", zap.Error(err))
-			log.Fatalf("Erro ao conectar ao Redis: %v", err)
-		}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		rateLimiterStorage = redisStorage
-	} else {
-		rateLimiterStorage = storage.NewMemoryStorage()
-		config.Logger.Warn("Usando armazenamento em memória (Redis não configurado)")
+	rateLimiterMetrics := metrics.New()
+	rateLimiterStorage := newStorage(rateLimiterMetrics)
+	snapshot := setupSnapshot(rateLimiterStorage)
+
+	rateLimiterStorage = metrics.NewInstrumentedStorage(rateLimiterStorage, rateLimiterMetrics)
+
+	failureModeIP, ok := limiter.ParseFailureMode(config.Cfg.FailureModeIP)
+	if !ok {
+		config.Logger.Fatal("FAILURE_MODE_IP inválido", zap.String("value", config.Cfg.FailureModeIP))
+	}
+	failureModeToken, ok := limiter.ParseFailureMode(config.Cfg.FailureModeToken)
+	if !ok {
+		config.Logger.Fatal("FAILURE_MODE_TOKEN inválido", zap.String("value", config.Cfg.FailureModeToken))
+	}
+
+	countedStatusesIP, err := limiter.ParseStatusFilter(config.Cfg.CountedStatusesIP)
+	if err != nil {
+		config.Logger.Fatal("COUNTED_STATUSES_IP inválido", zap.Error(err))
+	}
+	countedStatusesToken, err := limiter.ParseStatusFilter(config.Cfg.CountedStatusesToken)
+	if err != nil {
+		config.Logger.Fatal("COUNTED_STATUSES_TOKEN inválido", zap.Error(err))
 	}
 
 	rateLimiterService := limiter.NewRateLimiterService(rateLimiterStorage, limiter.RateLimiterConfig{
@@ -37,19 +69,312 @@ func main() {
 		BlockTimePerToken:     config.Cfg.BlockTimePerToken,
 		DefaultBlockTimeIP:    config.Cfg.DefaultBlockTimeIP,
 		DefaultBlockTimeToken: config.Cfg.DefaultBlockTimeToken,
-	}, config.Logger)
+		FailureModeIP:         failureModeIP,
+		FailureModeToken:      failureModeToken,
+		StorageTimeout:        time.Duration(config.Cfg.StorageTimeoutMs) * time.Millisecond,
+		PenaltyIP:             penaltyPolicy(config.Cfg.PenaltyStepsIP, config.Cfg.PenaltyDecayIP),
+		PenaltyToken:          penaltyPolicy(config.Cfg.PenaltyStepsToken, config.Cfg.PenaltyDecayToken),
+		Login:                 loginPolicy(config.Cfg.Login),
+		CountedStatusesIP:     countedStatusesIP,
+		CountedStatusesToken:  countedStatusesToken,
+	}, config.Logger).WithMetrics(rateLimiterMetrics)
 
 	r := gin.Default()
+	// Registrados antes do middleware para que scraping e probes não consumam a cota de ninguém
+	r.GET("/metrics", gin.WrapH(rateLimiterMetrics.Handler()))
+	health.NewChecker(rateLimiterStorage, time.Duration(config.Cfg.HealthDegradedLatency)*time.Millisecond).
+		WithFallback(servingWithoutStorage(failureModeIP, failureModeToken)).
+		Register(r)
 	r.Use(limiter.RateLimiterMiddleware(rateLimiterService))
 
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "Requisição permitida"})
 	})
 
-	port := config.Cfg.ServerPort
-	fmt.Println("Servidor rodando na porta", port)
-	if err := r.Run(":" + port); err != nil {
-		config.Logger.Fatal("Erro ao iniciar servidor", zap.Error(err))
+	servers := []*http.Server{{Addr: ":" + config.Cfg.ServerPort, Handler: r}}
+	if config.Cfg.AdminToken != "" {
+		servers = append(servers, newAdminServer(rateLimiterStorage, snapshot))
+		config.Logger.Info("API administrativa rodando", zap.String("port", config.Cfg.AdminPort))
+	} else {
+		config.Logger.Warn("API administrativa desabilitada (ADMIN_TOKEN não configurado)")
+	}
+
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				config.Logger.Fatal("Erro ao iniciar servidor", zap.String("addr", srv.Addr), zap.Error(err))
+			}
+		}(srv)
+	}
+	fmt.Println("Servidor rodando na porta", config.Cfg.ServerPort)
+
+	<-ctx.Done()
+	config.Logger.Info("Encerrando servidor")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			config.Logger.Warn("Erro ao encerrar servidor", zap.String("addr", srv.Addr), zap.Error(err))
+		}
+	}
+
+	// O snapshot final é gravado depois que as requisições em andamento terminaram
+	if snapshot != nil {
+		if err := snapshot(); err != nil {
+			config.Logger.Error("Erro ao gravar snapshot", zap.Error(err))
+		}
+	}
+}
+
+// setupSnapshot restaura o estado salvo do armazenamento em memória e devolve a função
+// que grava um novo snapshot, ou nil se o backend não for memória ou SNAPSHOT_PATH estiver vazio.
+func setupSnapshot(rateLimiterStorage storage.RateLimiterStorage) admin.SnapshotFunc {
+	memoryStorage, isMemory := rateLimiterStorage.(*storage.MemoryRateLimiterStorage)
+	path := config.Cfg.SnapshotPath
+	if !isMemory || path == "" {
+		return nil
+	}
+
+	switch err := memoryStorage.LoadSnapshot(path); {
+	case err == nil:
+		config.Logger.Info("Estado restaurado do snapshot", zap.String("path", path))
+	case errors.Is(err, os.ErrNotExist):
+		config.Logger.Info("Nenhum snapshot encontrado", zap.String("path", path))
+	default:
+		config.Logger.Error("Erro ao restaurar snapshot; iniciando sem estado", zap.String("path", path), zap.Error(err))
+	}
+
+	return func() error {
+		if err := memoryStorage.SaveSnapshot(path); err != nil {
+			return err
+		}
+		config.Logger.Info("Snapshot gravado", zap.String("path", path))
+		return nil
+	}
+}
+
+func newStorage(rateLimiterMetrics *metrics.Metrics) storage.RateLimiterStorage {
+	switch config.Cfg.StorageBackend {
+	case "postgres":
+		postgresStorage, err := storage.NewPostgresStorage(context.Background(), config.Cfg.PostgresDSN)
+		if err != nil {
+			config.Logger.Fatal("Erro ao conectar ao Postgres", zap.Error(err))
+		}
+		go postgresStorage.RunCleanup(context.Background(), time.Duration(config.Cfg.PostgresCleanupSecs)*time.Second, func(err error) {
+			config.Logger.Warn("Erro ao limpar registros expirados do Postgres", zap.Error(err))
+		})
+		return postgresStorage
+	case "bolt":
+		boltStorage, err := storage.NewBoltStorage(config.Cfg.BoltPath)
+		if err != nil {
+			config.Logger.Fatal("Erro ao abrir o arquivo do bbolt", zap.String("path", config.Cfg.BoltPath), zap.Error(err))
+		}
+		go boltStorage.RunCleanup(context.Background(), time.Duration(config.Cfg.BoltCleanupSecs)*time.Second, func(err error) {
+			config.Logger.Warn("Erro ao limpar registros expirados do bbolt", zap.Error(err))
+		})
+		return boltStorage
+	case "gossip":
+		return newGossipStorage()
+	case "memory":
+		return newMemoryStorage(rateLimiterMetrics)
+	case "", "redis":
+	default:
+		config.Logger.Fatal("STORAGE_BACKEND inválido", zap.String("value", config.Cfg.StorageBackend))
+	}
+
+	redisAddrs := config.Cfg.RedisAddrs()
+	if len(redisAddrs) == 0 {
+		config.Logger.Warn("Usando armazenamento em memória (Redis não configurado)")
+		return newMemoryStorage(rateLimiterMetrics)
+	}
+
+	redisCfg, err := storage.RedisConfigFromEnv(config.Cfg)
+	if err != nil {
+		config.Logger.Fatal("Configuração de TLS do Redis inválida", zap.Error(err))
+	}
+	if config.Cfg.RedisTLS.Enabled && config.Cfg.RedisTLS.InsecureSkipVerify {
+		config.Logger.Warn("Verificação do certificado do Redis desabilitada (REDIS_TLS_INSECURE_SKIP_VERIFY)")
+	}
+
+	var redisStorage interface {
+		storage.RateLimiterStorage
+		storage.InvalidationSubscriber
+	}
+	if config.Cfg.RedisMode == "sharded" {
+		redisStorage = newShardedRedis(redisCfg)
+	} else {
+		redisStorage, err = storage.NewRedisStorageFromConfig(redisCfg)
+		if err != nil {
+			config.Logger.Fatal("Configuração do Redis inválida", zap.Error(err))
+		}
+	}
+
+	if err := redisStorage.Ping(context.Background()); err != nil {
+		config.Logger.Fatal("Erro ao conectar ao Redis", zap.Error(err))
 	}
 
+	var rateLimiterStorage storage.RateLimiterStorage = redisStorage
+	if config.Cfg.CircuitBreaker.Enabled {
+		circuitBreaker = newCircuitBreaker(redisStorage, newMemoryStorage(rateLimiterMetrics))
+		rateLimiterStorage = circuitBreaker
+	}
+	if config.Cfg.BlockCache.Enabled {
+		rateLimiterStorage = newBlockCache(rateLimiterStorage, redisStorage)
+	}
+	if config.Cfg.CounterBatch.Enabled {
+		rateLimiterStorage = newBatchedCounters(rateLimiterStorage)
+	}
+	return rateLimiterStorage
+}
+
+// penaltyPolicy converte as etapas e o decaimento, em segundos, da configuração.
+func penaltyPolicy(stepsSeconds []int, decaySeconds int) limiter.PenaltyPolicy {
+	penalty := limiter.PenaltyPolicy{Decay: time.Duration(decaySeconds) * time.Second}
+	for _, seconds := range stepsSeconds {
+		penalty.Steps = append(penalty.Steps, time.Duration(seconds)*time.Second)
+	}
+	return penalty
+}
+
+func loginPolicy(loginCfg config.LoginConfig) limiter.LoginPolicy {
+	return limiter.LoginPolicy{
+		Paths:                loginCfg.Paths,
+		UsernameField:        loginCfg.UsernameField,
+		MaxFailuresPerIP:     loginCfg.MaxFailuresPerIP,
+		MaxFailuresPerUser:   loginCfg.MaxFailuresPerUser,
+		MaxFailuresPerUserIP: loginCfg.MaxFailuresPerUserIP,
+		Window:               time.Duration(loginCfg.WindowSeconds) * time.Second,
+		BlockTime:            time.Duration(loginCfg.BlockTimeSeconds) * time.Second,
+		FailureStatuses:      loginCfg.FailureStatuses,
+	}
+}
+
+func newAdminServer(rateLimiterStorage storage.RateLimiterStorage, snapshot admin.SnapshotFunc) *http.Server {
+	r := gin.New()
+	r.Use(gin.Recovery())
+
+	adminGroup := r.Group("/admin", admin.AuthMiddleware(config.Cfg.AdminToken))
+	admin.NewHandler(rateLimiterStorage, config.Logger).WithSnapshot(snapshot).Register(adminGroup)
+
+	return &http.Server{Addr: ":" + config.Cfg.AdminPort, Handler: r}
+}
+
+// newGossipStorage sobe o servidor que recebe as contagens dos pares em GOSSIP_ADDR
+// e começa a enviar as contagens locais a GOSSIP_PEERS.
+func newGossipStorage() *storage.GossipStorage {
+	gossipCfg := config.Cfg.Gossip
+	nodeID := gossipCfg.NodeID
+	if nodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			config.Logger.Fatal("Defina GOSSIP_NODE_ID", zap.Error(err))
+		}
+		nodeID = hostname
+	}
+
+	gossipStorage := storage.NewGossipStorage(storage.GossipOptions{
+		NodeID:   nodeID,
+		Peers:    gossipCfg.Peers,
+		Interval: time.Duration(gossipCfg.IntervalMs) * time.Millisecond,
+		Token:    gossipCfg.Token,
+	})
+
+	go func() {
+		if err := gossipStorage.ListenAndServe(gossipCfg.Addr); err != nil {
+			config.Logger.Fatal("Erro ao iniciar servidor de gossip", zap.String("addr", gossipCfg.Addr), zap.Error(err))
+		}
+	}()
+	go gossipStorage.Run(context.Background(), func(err error) {
+		config.Logger.Warn("Erro ao enviar contagens aos pares", zap.Error(err))
+	})
+
+	config.Logger.Info("Gossip entre instâncias habilitado",
+		zap.String("node_id", nodeID), zap.String("addr", gossipCfg.Addr), zap.Strings("peers", gossipCfg.Peers))
+	return gossipStorage
+}
+
+// newShardedRedis distribui as chaves entre os nós de REDIS_SHARD_ADDRS e acompanha a saúde deles.
+func newShardedRedis(redisCfg storage.RedisConfig) *storage.ShardedStorage {
+	sharded, err := storage.NewShardedRedisStorage(redisCfg, storage.ShardedOptions{
+		HealthInterval: time.Duration(config.Cfg.RedisShardHealthMs) * time.Millisecond,
+		OnHealthChange: func(node string, healthy bool) {
+			config.Logger.Warn("Nó Redis mudou de estado", zap.String("addr", node), zap.Bool("healthy", healthy))
+		},
+	})
+	if err != nil {
+		config.Logger.Fatal("Configuração do Redis inválida", zap.Error(err))
+	}
+	sharded.CheckHealth(context.Background())
+	go sharded.Run(context.Background())
+	return sharded
+}
+
+// newMemoryStorage aplica MEMORY_MAX_ENTRIES/MEMORY_MAX_BYTES para que chaves rotativas não esgotem a memória.
+func newMemoryStorage(rateLimiterMetrics *metrics.Metrics) *storage.MemoryRateLimiterStorage {
+	return storage.NewMemoryStorageWithOptions(storage.MemoryOptions{
+		MaxEntries: config.Cfg.MemoryMaxEntries,
+		MaxBytes:   config.Cfg.MemoryMaxBytes,
+		OnEvict:    rateLimiterMetrics.ObserveEviction,
+	})
+}
+
+// circuitBreaker fica acessível ao /readyz, já que os decoradores por cima dele escondem o estado.
+var circuitBreaker *storage.CircuitBreakerStorage
+
+// servingWithoutStorage indica se a instância continua atendendo com o storage fora do ar:
+// o circuito aberto serve do fallback em memória, e os modos open/local decidem sem o storage.
+func servingWithoutStorage(failureModeIP, failureModeToken limiter.FailureMode) func() bool {
+	return func() bool {
+		if circuitBreaker != nil && circuitBreaker.State() != storage.CircuitClosed {
+			return true
+		}
+		return failureModeIP != limiter.FailClosed && failureModeToken != limiter.FailClosed
+	}
+}
+
+func newCircuitBreaker(primary, fallback storage.RateLimiterStorage) *storage.CircuitBreakerStorage {
+	cbConfig := config.Cfg.CircuitBreaker
+	return storage.NewCircuitBreakerStorage(primary, fallback, storage.CircuitBreakerOptions{
+		FailureThreshold: cbConfig.FailureThreshold,
+		LatencyThreshold: time.Duration(cbConfig.LatencyMs) * time.Millisecond,
+		OpenTimeout:      time.Duration(cbConfig.OpenSeconds) * time.Second,
+		HalfOpenProbes:   cbConfig.HalfOpenProbes,
+		OnStateChange: func(from, to storage.CircuitState) {
+			config.Logger.Warn("Circuit breaker do Redis mudou de estado",
+				zap.String("from", from.String()),
+				zap.String("to", to.String()),
+			)
+		},
+	})
+}
+
+// newBlockCache coloca o cache de bloqueios na frente do Redis e o mantém coerente
+// com desbloqueios feitos por qualquer instância via pub/sub.
+func newBlockCache(next storage.RateLimiterStorage, subscriber storage.InvalidationSubscriber) *storage.BlockCacheStorage {
+	cache := storage.NewBlockCacheStorage(next, storage.BlockCacheOptions{
+		MaxTTL:     time.Duration(config.Cfg.BlockCache.MaxTTLSeconds) * time.Second,
+		MaxEntries: config.Cfg.BlockCache.MaxEntries,
+	})
+
+	go func() {
+		for {
+			err := subscriber.SubscribeInvalidations(context.Background(), cache.Invalidate)
+			config.Logger.Warn("Assinatura de invalidações do cache de bloqueios encerrada", zap.Error(err))
+			time.Sleep(time.Second)
+		}
+	}()
+	return cache
+}
+
+func newBatchedCounters(next storage.RateLimiterStorage) *storage.BatchedCounterStorage {
+	batched := storage.NewBatchedCounterStorage(next, storage.BatchedCounterOptions{
+		FlushInterval: time.Duration(config.Cfg.CounterBatch.FlushMs) * time.Millisecond,
+		MaxPending:    config.Cfg.CounterBatch.MaxPending,
+		OnFlushError: func(key string, err error) {
+			config.Logger.Warn("Erro ao enviar contadores acumulados", zap.String("key", key), zap.Error(err))
+		},
+	})
+	go batched.Run(context.Background())
+	return batched
 }
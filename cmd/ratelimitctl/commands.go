@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"rate-limiter/config"
+	"rate-limiter/internal/admin"
+	"rate-limiter/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backend é o subconjunto de operações usado pela ferramenta. É satisfeito
+// tanto pelo RateLimiterStorage (acesso direto) quanto pelo admin.Client.
+type backend interface {
+	ListBlockedKeys(ctx context.Context) ([]string, error)
+	InspectKey(ctx context.Context, key string) (storage.KeyInfo, error)
+	BlockKey(ctx context.Context, key string, duration time.Duration) error
+	UnblockKey(ctx context.Context, key string) error
+	ResetKey(ctx context.Context, key string) error
+}
+
+type blockEntry struct {
+	Key      string
+	Duration time.Duration
+}
+
+func dispatch(ctx context.Context, target backend, command string, args []string, stdout io.Writer) error {
+	switch command {
+	case "status":
+		if len(args) != 1 {
+			return errors.New("uso: status <chave>")
+		}
+		info, err := target.InspectKey(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		printStatus(stdout, info)
+		return nil
+	case "block":
+		if len(args) != 2 {
+			return errors.New("uso: block <chave> <segundos>")
+		}
+		seconds, err := strconv.Atoi(args[1])
+		if err != nil || seconds <= 0 {
+			return fmt.Errorf("duração inválida: %q", args[1])
+		}
+		if err := target.BlockKey(ctx, args[0], time.Duration(seconds)*time.Second); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s bloqueada por %ds\n", args[0], seconds)
+		return nil
+	case "unblock":
+		if len(args) != 1 {
+			return errors.New("uso: unblock <chave>")
+		}
+		if err := target.UnblockKey(ctx, args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s desbloqueada\n", args[0])
+		return nil
+	case "reset":
+		if len(args) != 1 {
+			return errors.New("uso: reset <chave>")
+		}
+		if err := target.ResetKey(ctx, args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "contador de %s zerado\n", args[0])
+		return nil
+	case "import":
+		if len(args) != 1 {
+			return errors.New("uso: import <arquivo.csv>")
+		}
+		return importCommand(ctx, target, args[0], stdout)
+	case "export":
+		format := "json"
+		if len(args) == 1 {
+			format = args[0]
+		}
+		return exportCommand(ctx, target, format, stdout)
+	default:
+		return fmt.Errorf("comando desconhecido: %q", command)
+	}
+}
+
+func printStatus(w io.Writer, info storage.KeyInfo) {
+	fmt.Fprintf(w, "chave:      %s\n", info.Key)
+	fmt.Fprintf(w, "contagem:   %d\n", info.Count)
+	if info.CountTTL > 0 {
+		fmt.Fprintf(w, "janela:     expira em %s\n", info.CountTTL.Round(time.Second))
+	}
+	if info.Blocked {
+		fmt.Fprintf(w, "bloqueada:  sim (restam %s)\n", info.BlockRemaining.Round(time.Second))
+	} else {
+		fmt.Fprintln(w, "bloqueada:  não")
+	}
+}
+
+func importCommand(ctx context.Context, target backend, path string, stdout io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries, err := parseBlocksCSV(file)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := target.BlockKey(ctx, entry.Key, entry.Duration); err != nil {
+			return fmt.Errorf("erro ao bloquear %s: %w", entry.Key, err)
+		}
+	}
+	fmt.Fprintf(stdout, "%d bloqueios importados\n", len(entries))
+	return nil
+}
+
+// parseBlocksCSV lê linhas "chave,segundos". Um cabeçalho na primeira linha é
+// ignorado. O arquivo é validado por inteiro antes de qualquer escrita.
+func parseBlocksCSV(r io.Reader) ([]blockEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]blockEntry, 0, len(records))
+	for i, record := range records {
+		key := strings.TrimSpace(record[0])
+		seconds, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil && i == 0 {
+			continue
+		}
+		if err != nil || seconds <= 0 || key == "" {
+			return nil, fmt.Errorf("linha %d inválida: %q", i+1, strings.Join(record, ","))
+		}
+		entries = append(entries, blockEntry{Key: key, Duration: time.Duration(seconds) * time.Second})
+	}
+	return entries, nil
+}
+
+func exportCommand(ctx context.Context, target backend, format string, stdout io.Writer) error {
+	keys, err := target.ListBlockedKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	states := make([]admin.KeyResponse, 0, len(keys))
+	for _, key := range keys {
+		info, err := target.InspectKey(ctx, key)
+		if err != nil {
+			return fmt.Errorf("erro ao inspecionar %s: %w", key, err)
+		}
+		states = append(states, admin.NewKeyResponse(info))
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(states)
+	case "csv":
+		// O formato é compatível com o comando import; o arredondamento para cima evita
+		// exportar como 0 (rejeitado pelo import) um bloqueio com menos de 1s restante,
+		// e bloqueios que venceram entre a listagem e a inspeção ficam de fora
+		writer := csv.NewWriter(stdout)
+		writer.Write([]string{"key", "block_remaining_seconds"})
+		for _, state := range states {
+			if state.BlockRemainingSeconds <= 0 {
+				continue
+			}
+			writer.Write([]string{state.Key, strconv.Itoa(int(math.Ceil(state.BlockRemainingSeconds)))})
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("formato de exportação desconhecido: %q", format)
+	}
+}
+
+func validateCommand(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("uso: validate <arquivo.env>")
+	}
+	cfg, err := config.ValidateFile(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s válido: %d req/IP, %d req/token, %d overrides de IP, %d overrides de token\n",
+		args[0], cfg.RateLimitPerIP, cfg.RateLimitPerToken, len(cfg.BlockTimePerIP), len(cfg.BlockTimePerToken))
+	return nil
+}
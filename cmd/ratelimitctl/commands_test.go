@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rate-limiter/internal/admin"
+	"rate-limiter/internal/storage"
+	"rate-limiter/internal/storage/storagetest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBlocksCSV(t *testing.T) {
+	entries, err := parseBlocksCSV(strings.NewReader("key,seconds\n192.168.1.1,120\ntoken123, 600\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []blockEntry{
+		{Key: "192.168.1.1", Duration: 120 * time.Second},
+		{Key: "token123", Duration: 600 * time.Second},
+	}, entries)
+
+	// Sem cabeçalho também deve funcionar
+	entries, err = parseBlocksCSV(strings.NewReader("192.168.1.1,120\n"))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = parseBlocksCSV(strings.NewReader("192.168.1.1,120\n192.168.1.2,abc\n"))
+	assert.ErrorContains(t, err, "linha 2")
+
+	_, err = parseBlocksCSV(strings.NewReader("192.168.1.1,120,extra\n"))
+	assert.Error(t, err)
+}
+
+func TestDispatch_StatusBlockUnblockReset(t *testing.T) {
+	ctx := context.Background()
+	memStorage := storage.NewMemoryStorage()
+	memStorage.IncrementRequest(ctx, "192.168.1.1")
+
+	var out bytes.Buffer
+	assert.NoError(t, dispatch(ctx, memStorage, "block", []string{"192.168.1.1", "60"}, &out))
+
+	out.Reset()
+	assert.NoError(t, dispatch(ctx, memStorage, "status", []string{"192.168.1.1"}, &out))
+	assert.Contains(t, out.String(), "contagem:   1")
+	assert.Contains(t, out.String(), "bloqueada:  sim")
+
+	assert.NoError(t, dispatch(ctx, memStorage, "unblock", []string{"192.168.1.1"}, &out))
+	assert.NoError(t, dispatch(ctx, memStorage, "reset", []string{"192.168.1.1"}, &out))
+
+	info, _ := memStorage.InspectKey(ctx, "192.168.1.1")
+	assert.False(t, info.Blocked)
+	assert.Equal(t, 0, info.Count)
+
+	assert.Error(t, dispatch(ctx, memStorage, "block", []string{"192.168.1.1", "-5"}, &out))
+	assert.Error(t, dispatch(ctx, memStorage, "desconhecido", nil, &out))
+}
+
+func TestDispatch_ImportAndExport(t *testing.T) {
+	ctx := context.Background()
+	memStorage := storage.NewMemoryStorage()
+	path := filepath.Join(t.TempDir(), "blocks.csv")
+	os.WriteFile(path, []byte("192.168.1.1,120\ntoken123,600\n"), 0o600)
+
+	var out bytes.Buffer
+	assert.NoError(t, dispatch(ctx, memStorage, "import", []string{path}, &out))
+	assert.Contains(t, out.String(), "2 bloqueios importados")
+
+	out.Reset()
+	assert.NoError(t, dispatch(ctx, memStorage, "export", []string{"json"}, &out))
+
+	var states []admin.KeyResponse
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &states))
+	assert.Len(t, states, 2)
+	assert.Equal(t, "192.168.1.1", states[0].Key)
+	assert.True(t, states[0].Blocked)
+
+	// O CSV exportado deve poder ser reimportado
+	out.Reset()
+	assert.NoError(t, dispatch(ctx, memStorage, "export", []string{"csv"}, &out))
+	entries, err := parseBlocksCSV(&out)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestDispatch_ExportRoundsUpExpiringBlocks(t *testing.T) {
+	ctx := context.Background()
+	clock := storagetest.NewClock()
+	memStorage := storage.NewMemoryStorageWithOptions(storage.MemoryOptions{Clock: clock})
+	memStorage.BlockKey(ctx, "192.168.1.1", time.Minute)
+	clock.Advance(59*time.Second + 500*time.Millisecond)
+
+	var out bytes.Buffer
+	assert.NoError(t, dispatch(ctx, memStorage, "export", []string{"csv"}, &out))
+	entries, err := parseBlocksCSV(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, []blockEntry{{Key: "192.168.1.1", Duration: time.Second}}, entries)
+}
+
+// staleListStorage devolve uma listagem antiga, com bloqueios que já venceram
+type staleListStorage struct {
+	*storage.MemoryRateLimiterStorage
+	keys []string
+}
+
+func (s *staleListStorage) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	return s.keys, nil
+}
+
+func TestDispatch_ExportSkipsBlocksExpiredAfterListing(t *testing.T) {
+	ctx := context.Background()
+	memStorage := storage.NewMemoryStorage()
+	memStorage.BlockKey(ctx, "192.168.1.1", time.Minute)
+	target := &staleListStorage{MemoryRateLimiterStorage: memStorage, keys: []string{"192.168.1.1", "192.168.1.2"}}
+
+	var out bytes.Buffer
+	assert.NoError(t, dispatch(ctx, target, "export", []string{"csv"}, &out))
+	assert.NotContains(t, out.String(), "192.168.1.2")
+
+	// O arquivo exportado continua importável
+	entries, err := parseBlocksCSV(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, []blockEntry{{Key: "192.168.1.1", Duration: time.Minute}}, entries)
+}
+
+func TestRun_Validate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.env")
+	os.WriteFile(path, []byte("RATE_LIMIT_PER_IP=0\n"), 0o600)
+
+	var out, errOut bytes.Buffer
+	err := run([]string{"validate", path}, &out, &errOut)
+	assert.ErrorContains(t, err, "RATE_LIMIT_PER_IP")
+}
+
+func TestRun_RequiresBackend(t *testing.T) {
+	var out, errOut bytes.Buffer
+	err := run([]string{"status", "192.168.1.1"}, &out, &errOut)
+	assert.ErrorContains(t, err, "-redis, -env ou -admin")
+}
+
+func TestRun_RedisFromEnvFile(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("plantao", "s3cr3t")
+	path := filepath.Join(t.TempDir(), "redis.env")
+	os.WriteFile(path, []byte("REDIS_ADDR="+server.Addr()+"\nREDIS_USERNAME=plantao\nREDIS_PASSWORD=s3cr3t\n"), 0o600)
+
+	// Usuário da ACL vem do arquivo, como no serviço
+	var out, errOut bytes.Buffer
+	assert.NoError(t, run([]string{"-env", path, "block", "192.168.1.1", "60"}, &out, &errOut))
+	assert.True(t, server.Exists("rate_limiter:block:192.168.1.1"))
+
+	// -redis-password informada substitui a do arquivo
+	err := run([]string{"-env", path, "-redis-password", "errada", "status", "192.168.1.1"}, &out, &errOut)
+	assert.ErrorContains(t, err, "erro ao conectar ao Redis")
+}
+
+func TestRun_RedisShardedMode(t *testing.T) {
+	nodes := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+	t.Setenv("REDIS_MODE", "sharded")
+
+	var out, errOut bytes.Buffer
+	addrs := nodes[0].Addr() + "," + nodes[1].Addr()
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, run([]string{"-redis", addrs, "block", fmt.Sprintf("10.0.0.%d", i), "60"}, &out, &errOut))
+	}
+
+	// As chaves foram distribuídas entre os dois nós
+	assert.NotEmpty(t, nodes[0].Keys())
+	assert.NotEmpty(t, nodes[1].Keys())
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"rate-limiter/config"
+	"rate-limiter/internal/admin"
+	"rate-limiter/internal/storage"
+	"strings"
+)
+
+const usage = `Uso: ratelimitctl [flags] <comando> [argumentos]
+
+Comandos:
+  status <chave>              Mostra contagem, TTL e bloqueio da chave
+  block <chave> <segundos>    Bloqueia a chave manualmente
+  unblock <chave>             Remove o bloqueio da chave
+  reset <chave>               Zera o contador da chave
+  import <arquivo.csv>        Importa bloqueios (chave,segundos por linha)
+  export [json|csv]           Exporta as chaves bloqueadas e seu estado
+  validate <arquivo.env>      Valida um arquivo de configuração offline
+
+O acesso direto ao Redis usa as mesmas variáveis REDIS_* do serviço (modo, endereços,
+ACL, TLS e pool), lidas do ambiente ou do arquivo em -env.
+
+Flags:
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "erro:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("ratelimitctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	redisAddr := flags.String("redis", "", "endereços do Redis, separados por vírgula (substituem os do REDIS_MODE)")
+	envFile := flags.String("env", "", "arquivo .env com as variáveis REDIS_* (padrão: ambiente do processo)")
+	redisPassword := flags.String("redis-password", "", "senha do Redis (substitui REDIS_PASSWORD)")
+	redisDB := flags.Int("redis-db", 0, "banco do Redis (substitui REDIS_DB)")
+	adminURL := flags.String("admin", "", "URL da API administrativa (ex: http://localhost:9091)")
+	adminToken := flags.String("token", os.Getenv("ADMIN_TOKEN"), "token da API administrativa")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("nenhum comando informado")
+	}
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	ctx := context.Background()
+
+	// validate roda offline e não precisa de backend
+	if command == "validate" {
+		return validateCommand(commandArgs, stdout)
+	}
+
+	var target backend
+	switch {
+	case *adminURL != "" && (*redisAddr != "" || *envFile != ""):
+		return errors.New("use apenas uma das flags -redis/-env ou -admin")
+	case *adminURL != "":
+		target = admin.NewClient(*adminURL, *adminToken)
+	case *redisAddr != "" || *envFile != "":
+		cfg := config.FromEnvironment()
+		if *envFile != "" {
+			var err error
+			if cfg, err = config.ReadFile(*envFile); err != nil {
+				return fmt.Errorf("erro ao ler %s: %w", *envFile, err)
+			}
+		}
+		if *redisAddr != "" {
+			cfg.SetRedisAddrs(strings.Split(*redisAddr, ","))
+		}
+		// Só as flags informadas substituem a configuração
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "redis-password":
+				cfg.RedisPassword = *redisPassword
+			case "redis-db":
+				cfg.RedisDB = *redisDB
+			}
+		})
+
+		redisStorage, err := newRedisStorage(cfg)
+		if err != nil {
+			return err
+		}
+		if err := redisStorage.Ping(ctx); err != nil {
+			return fmt.Errorf("erro ao conectar ao Redis: %w", err)
+		}
+		target = redisStorage
+	default:
+		return errors.New("informe -redis, -env ou -admin")
+	}
+
+	return dispatch(ctx, target, command, commandArgs, stdout)
+}
+
+// newRedisStorage monta o cliente como o serviço: REDIS_MODE=sharded distribui as chaves
+// entre os nós e os demais modos usam um único cliente (standalone, sentinel ou cluster).
+func newRedisStorage(cfg config.Config) (storage.RateLimiterStorage, error) {
+	if len(cfg.RedisAddrs()) == 0 {
+		return nil, fmt.Errorf("nenhum endereço do Redis para REDIS_MODE=%q", cfg.RedisMode)
+	}
+	redisCfg, err := storage.RedisConfigFromEnv(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("configuração de TLS do Redis inválida: %w", err)
+	}
+	if cfg.RedisMode == "sharded" {
+		return storage.NewShardedRedisStorage(redisCfg, storage.ShardedOptions{})
+	}
+	return storage.NewRedisStorageFromConfig(redisCfg)
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -16,34 +20,480 @@ type Config struct {
 	DefaultBlockTimeToken int
 	BlockTimePerIP        map[string]int
 	BlockTimePerToken     map[string]int
+	PenaltyStepsIP        []int
+	PenaltyStepsToken     []int
+	PenaltyDecayIP        int
+	PenaltyDecayToken     int
+	StorageBackend        string
+	PostgresDSN           string
+	PostgresCleanupSecs   int
+	BoltPath              string
+	BoltCleanupSecs       int
+	SnapshotPath          string
+	Gossip                GossipConfig
+	MemoryMaxEntries      int
+	MemoryMaxBytes        int
 	RedisAddr             string
 	RedisPassword         string
 	RedisDB               int
+	RedisMode             string
+	RedisMasterName       string
+	RedisSentinelAddrs    []string
+	RedisClusterAddrs     []string
+	RedisShardAddrs       []string
+	RedisShardHealthMs    int
+	RedisUsername         string
+	RedisSentinelPassword string
+	RedisTLS              RedisTLSConfig
+	RedisPool             RedisPoolConfig
 	ServerPort            string
 	LogLevel              string
+	TracingExporter       string
+	TracingServiceName    string
+	AdminPort             string
+	AdminToken            string
+	HealthDegradedLatency int
+	FailureModeIP         string
+	FailureModeToken      string
+	StorageTimeoutMs      int
+	CountedStatusesIP     string
+	CountedStatusesToken  string
+	CircuitBreaker        CircuitBreakerConfig
+	BlockCache            BlockCacheConfig
+	CounterBatch          CounterBatchConfig
+	Login                 LoginConfig
+}
+
+type RedisTLSConfig struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// RedisPoolConfig usa zero para manter o padrão do go-redis.
+type RedisPoolConfig struct {
+	Size           int
+	MinIdleConns   int
+	PoolTimeoutMs  int
+	DialTimeoutMs  int
+	ReadTimeoutMs  int
+	WriteTimeoutMs int
+	MaxRetries     int
+}
+
+type CircuitBreakerConfig struct {
+	Enabled          bool
+	FailureThreshold int
+	LatencyMs        int
+	OpenSeconds      int
+	HalfOpenProbes   int
+}
+
+type BlockCacheConfig struct {
+	Enabled       bool
+	MaxTTLSeconds int
+	MaxEntries    int
+}
+
+// CounterBatchConfig liga o modo aproximado em que cada instância acumula os
+// incrementos localmente e envia apenas os deltas ao Redis.
+type CounterBatchConfig struct {
+	Enabled    bool
+	FlushMs    int
+	MaxPending int
+}
+
+// LoginConfig liga a proteção contra força bruta nas rotas de LOGIN_PATHS; vazio desliga.
+type LoginConfig struct {
+	Paths                []string
+	UsernameField        string
+	MaxFailuresPerIP     int
+	MaxFailuresPerUser   int
+	MaxFailuresPerUserIP int
+	WindowSeconds        int
+	BlockTimeSeconds     int
+	FailureStatuses      []int
+}
+
+type GossipConfig struct {
+	NodeID     string
+	Addr       string
+	Peers      []string
+	IntervalMs int
+	Token      string
 }
 
 var Cfg Config
 
+// lookupFunc abstrai a origem das variáveis: o ambiente do processo ou um
+// arquivo .env lido sem alterar o ambiente (validação offline).
+type lookupFunc func(key string) (string, bool)
+
+var intKeys = []string{
+	"RATE_LIMIT_PER_IP",
+	"RATE_LIMIT_PER_TOKEN",
+	"DEFAULT_BLOCK_TIME_IP",
+	"DEFAULT_BLOCK_TIME_TOKEN",
+	"PENALTY_DECAY_IP",
+	"PENALTY_DECAY_TOKEN",
+	"LOGIN_MAX_FAILURES_PER_IP",
+	"LOGIN_MAX_FAILURES_PER_USER",
+	"LOGIN_MAX_FAILURES_PER_USER_IP",
+	"LOGIN_FAILURE_WINDOW_SECONDS",
+	"LOGIN_BLOCK_TIME_SECONDS",
+	"REDIS_DB",
+	"REDIS_SHARD_HEALTH_INTERVAL_MS",
+	"POSTGRES_CLEANUP_SECONDS",
+	"BOLT_CLEANUP_SECONDS",
+	"MEMORY_MAX_ENTRIES",
+	"MEMORY_MAX_BYTES",
+	"GOSSIP_INTERVAL_MS",
+	"HEALTH_DEGRADED_LATENCY_MS",
+	"STORAGE_TIMEOUT_MS",
+	"REDIS_POOL_SIZE",
+	"REDIS_MIN_IDLE_CONNS",
+	"REDIS_POOL_TIMEOUT_MS",
+	"REDIS_DIAL_TIMEOUT_MS",
+	"REDIS_READ_TIMEOUT_MS",
+	"REDIS_WRITE_TIMEOUT_MS",
+	"REDIS_MAX_RETRIES",
+	"CIRCUIT_BREAKER_FAILURES",
+	"CIRCUIT_BREAKER_LATENCY_MS",
+	"CIRCUIT_BREAKER_OPEN_SECONDS",
+	"CIRCUIT_BREAKER_HALF_OPEN_PROBES",
+	"BLOCK_CACHE_MAX_TTL_SECONDS",
+	"BLOCK_CACHE_MAX_ENTRIES",
+	"COUNTER_BATCH_FLUSH_MS",
+	"COUNTER_BATCH_MAX_PENDING",
+}
+
+var blockTimeListKeys = []string{
+	"BLOCK_TIME_PER_IP",
+	"BLOCK_TIME_PER_TOKEN",
+}
+
+var intListKeys = []string{
+	"PENALTY_STEPS_IP",
+	"PENALTY_STEPS_TOKEN",
+	"LOGIN_FAILURE_STATUSES",
+}
+
 func LoadConfig() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("[WARN] Arquivo .env não encontrado, usando variáveis de ambiente")
 	}
 
-	Cfg = Config{
-		RateLimitPerIP:        getEnvAsInt("RATE_LIMIT_PER_IP", 5),
-		RateLimitPerToken:     getEnvAsInt("RATE_LIMIT_PER_TOKEN", 100),
-		DefaultBlockTimeIP:    getEnvAsInt("DEFAULT_BLOCK_TIME_IP", 300),
-		DefaultBlockTimeToken: getEnvAsInt("DEFAULT_BLOCK_TIME_TOKEN", 300),
-		BlockTimePerIP:        parseBlockTimeList(getEnv("BLOCK_TIME_PER_IP", "")),
-		BlockTimePerToken:     parseBlockTimeList(getEnv("BLOCK_TIME_PER_TOKEN", "")),
-		RedisAddr:             getEnv("REDIS_ADDR", "localhost:6379"),
-		RedisDB:               getEnvAsInt("REDIS_DB", 0),
-		ServerPort:            getEnv("SERVER_PORT", "8080"),
-		LogLevel:              getEnv("LOG_LEVEL", "info"),
+	Cfg = loadFrom(os.LookupEnv)
+}
+
+func loadFrom(lookup lookupFunc) Config {
+	return Config{
+		RateLimitPerIP:        getEnvAsInt(lookup, "RATE_LIMIT_PER_IP", 5),
+		RateLimitPerToken:     getEnvAsInt(lookup, "RATE_LIMIT_PER_TOKEN", 100),
+		DefaultBlockTimeIP:    getEnvAsInt(lookup, "DEFAULT_BLOCK_TIME_IP", 300),
+		DefaultBlockTimeToken: getEnvAsInt(lookup, "DEFAULT_BLOCK_TIME_TOKEN", 300),
+		BlockTimePerIP:        parseBlockTimeList(getEnv(lookup, "BLOCK_TIME_PER_IP", "")),
+		BlockTimePerToken:     parseBlockTimeList(getEnv(lookup, "BLOCK_TIME_PER_TOKEN", "")),
+		PenaltyStepsIP:        getEnvAsIntList(lookup, "PENALTY_STEPS_IP"),
+		PenaltyStepsToken:     getEnvAsIntList(lookup, "PENALTY_STEPS_TOKEN"),
+		PenaltyDecayIP:        getEnvAsInt(lookup, "PENALTY_DECAY_IP", 86400),
+		PenaltyDecayToken:     getEnvAsInt(lookup, "PENALTY_DECAY_TOKEN", 86400),
+		StorageBackend:        getEnv(lookup, "STORAGE_BACKEND", "redis"),
+		PostgresDSN:           getEnv(lookup, "POSTGRES_DSN", ""),
+		PostgresCleanupSecs:   getEnvAsInt(lookup, "POSTGRES_CLEANUP_SECONDS", 60),
+		BoltPath:              getEnv(lookup, "BOLT_PATH", "data/rate-limiter.db"),
+		BoltCleanupSecs:       getEnvAsInt(lookup, "BOLT_CLEANUP_SECONDS", 60),
+		SnapshotPath:          getEnv(lookup, "SNAPSHOT_PATH", ""),
+		Gossip: GossipConfig{
+			NodeID:     getEnv(lookup, "GOSSIP_NODE_ID", ""),
+			Addr:       getEnv(lookup, "GOSSIP_ADDR", ":7946"),
+			Peers:      getEnvAsList(lookup, "GOSSIP_PEERS"),
+			IntervalMs: getEnvAsInt(lookup, "GOSSIP_INTERVAL_MS", 200),
+			Token:      getEnv(lookup, "GOSSIP_TOKEN", ""),
+		},
+		MemoryMaxEntries:      getEnvAsInt(lookup, "MEMORY_MAX_ENTRIES", 0),
+		MemoryMaxBytes:        getEnvAsInt(lookup, "MEMORY_MAX_BYTES", 0),
+		RedisAddr:             getEnv(lookup, "REDIS_ADDR", "localhost:6379"),
+		RedisPassword:         getEnv(lookup, "REDIS_PASSWORD", ""),
+		RedisDB:               getEnvAsInt(lookup, "REDIS_DB", 0),
+		RedisMode:             getEnv(lookup, "REDIS_MODE", "standalone"),
+		RedisMasterName:       getEnv(lookup, "REDIS_MASTER_NAME", ""),
+		RedisSentinelAddrs:    getEnvAsList(lookup, "REDIS_SENTINEL_ADDRS"),
+		RedisClusterAddrs:     getEnvAsList(lookup, "REDIS_CLUSTER_ADDRS"),
+		RedisShardAddrs:       getEnvAsList(lookup, "REDIS_SHARD_ADDRS"),
+		RedisShardHealthMs:    getEnvAsInt(lookup, "REDIS_SHARD_HEALTH_INTERVAL_MS", 1000),
+		RedisUsername:         getEnv(lookup, "REDIS_USERNAME", ""),
+		RedisSentinelPassword: getEnv(lookup, "REDIS_SENTINEL_PASSWORD", ""),
+		RedisTLS: RedisTLSConfig{
+			Enabled:            getEnvAsBool(lookup, "REDIS_TLS_ENABLED", false),
+			CAFile:             getEnv(lookup, "REDIS_TLS_CA_FILE", ""),
+			CertFile:           getEnv(lookup, "REDIS_TLS_CERT_FILE", ""),
+			KeyFile:            getEnv(lookup, "REDIS_TLS_KEY_FILE", ""),
+			ServerName:         getEnv(lookup, "REDIS_TLS_SERVER_NAME", ""),
+			InsecureSkipVerify: getEnvAsBool(lookup, "REDIS_TLS_INSECURE_SKIP_VERIFY", false),
+		},
+		RedisPool: RedisPoolConfig{
+			Size:           getEnvAsInt(lookup, "REDIS_POOL_SIZE", 0),
+			MinIdleConns:   getEnvAsInt(lookup, "REDIS_MIN_IDLE_CONNS", 0),
+			PoolTimeoutMs:  getEnvAsInt(lookup, "REDIS_POOL_TIMEOUT_MS", 0),
+			DialTimeoutMs:  getEnvAsInt(lookup, "REDIS_DIAL_TIMEOUT_MS", 0),
+			ReadTimeoutMs:  getEnvAsInt(lookup, "REDIS_READ_TIMEOUT_MS", 0),
+			WriteTimeoutMs: getEnvAsInt(lookup, "REDIS_WRITE_TIMEOUT_MS", 0),
+			MaxRetries:     getEnvAsInt(lookup, "REDIS_MAX_RETRIES", 0),
+		},
+		ServerPort:            getEnv(lookup, "SERVER_PORT", "8080"),
+		LogLevel:              getEnv(lookup, "LOG_LEVEL", "info"),
+		TracingExporter:       getEnv(lookup, "TRACING_EXPORTER", "none"),
+		TracingServiceName:    getEnv(lookup, "TRACING_SERVICE_NAME", "rate-limiter"),
+		AdminPort:             getEnv(lookup, "ADMIN_PORT", "9091"),
+		AdminToken:            getEnv(lookup, "ADMIN_TOKEN", ""),
+		HealthDegradedLatency: getEnvAsInt(lookup, "HEALTH_DEGRADED_LATENCY_MS", 100),
+		FailureModeIP:         getEnv(lookup, "FAILURE_MODE_IP", "open"),
+		FailureModeToken:      getEnv(lookup, "FAILURE_MODE_TOKEN", "open"),
+		StorageTimeoutMs:      getEnvAsInt(lookup, "STORAGE_TIMEOUT_MS", 100),
+		CountedStatusesIP:     getEnv(lookup, "COUNTED_STATUSES_IP", ""),
+		CountedStatusesToken:  getEnv(lookup, "COUNTED_STATUSES_TOKEN", ""),
+		CircuitBreaker: CircuitBreakerConfig{
+			Enabled:          getEnvAsBool(lookup, "CIRCUIT_BREAKER_ENABLED", false),
+			FailureThreshold: getEnvAsInt(lookup, "CIRCUIT_BREAKER_FAILURES", 5),
+			LatencyMs:        getEnvAsInt(lookup, "CIRCUIT_BREAKER_LATENCY_MS", 250),
+			OpenSeconds:      getEnvAsInt(lookup, "CIRCUIT_BREAKER_OPEN_SECONDS", 10),
+			HalfOpenProbes:   getEnvAsInt(lookup, "CIRCUIT_BREAKER_HALF_OPEN_PROBES", 3),
+		},
+		BlockCache: BlockCacheConfig{
+			Enabled:       getEnvAsBool(lookup, "BLOCK_CACHE_ENABLED", false),
+			MaxTTLSeconds: getEnvAsInt(lookup, "BLOCK_CACHE_MAX_TTL_SECONDS", 60),
+			MaxEntries:    getEnvAsInt(lookup, "BLOCK_CACHE_MAX_ENTRIES", 100000),
+		},
+		CounterBatch: CounterBatchConfig{
+			Enabled:    getEnvAsBool(lookup, "COUNTER_BATCH_ENABLED", false),
+			FlushMs:    getEnvAsInt(lookup, "COUNTER_BATCH_FLUSH_MS", 100),
+			MaxPending: getEnvAsInt(lookup, "COUNTER_BATCH_MAX_PENDING", 10),
+		},
+		Login: LoginConfig{
+			Paths:                getEnvAsList(lookup, "LOGIN_PATHS"),
+			UsernameField:        getEnv(lookup, "LOGIN_USERNAME_FIELD", "username"),
+			MaxFailuresPerIP:     getEnvAsInt(lookup, "LOGIN_MAX_FAILURES_PER_IP", 20),
+			MaxFailuresPerUser:   getEnvAsInt(lookup, "LOGIN_MAX_FAILURES_PER_USER", 10),
+			MaxFailuresPerUserIP: getEnvAsInt(lookup, "LOGIN_MAX_FAILURES_PER_USER_IP", 5),
+			WindowSeconds:        getEnvAsInt(lookup, "LOGIN_FAILURE_WINDOW_SECONDS", 900),
+			BlockTimeSeconds:     getEnvAsInt(lookup, "LOGIN_BLOCK_TIME_SECONDS", 900),
+			FailureStatuses:      getEnvAsIntList(lookup, "LOGIN_FAILURE_STATUSES"),
+		},
+	}
+}
+
+// ReadFile carrega a configuração de um arquivo .env sem aplicá-lo ao processo.
+func ReadFile(path string) (Config, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return loadFrom(mapLookup(values)), nil
+}
+
+// FromEnvironment carrega a configuração do ambiente do processo sem alterar Cfg.
+func FromEnvironment() Config {
+	return loadFrom(os.LookupEnv)
+}
+
+func mapLookup(values map[string]string) lookupFunc {
+	return func(key string) (string, bool) {
+		value, exists := values[key]
+		return value, exists
 	}
 }
 
+// ValidateFile lê um arquivo .env sem aplicá-lo ao processo e devolve todos os
+// problemas encontrados, inclusive valores que o LoadConfig ignoraria em silêncio.
+func ValidateFile(path string) (Config, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return Config{}, err
+	}
+	lookup := mapLookup(values)
+
+	var errs []error
+	for _, key := range intKeys {
+		if value, exists := values[key]; exists {
+			if _, err := strconv.Atoi(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: valor inteiro inválido %q", key, value))
+			}
+		}
+	}
+	for _, key := range blockTimeListKeys {
+		if err := validateBlockTimeList(values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	for _, key := range intListKeys {
+		for _, item := range getEnvAsList(lookup, key) {
+			if _, err := strconv.Atoi(item); err != nil {
+				errs = append(errs, fmt.Errorf("%s: valor inteiro inválido %q", key, item))
+			}
+		}
+	}
+
+	cfg := loadFrom(lookup)
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return cfg, errors.Join(errs...)
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	if c.RateLimitPerIP <= 0 {
+		errs = append(errs, errors.New("RATE_LIMIT_PER_IP deve ser maior que zero"))
+	}
+	if c.RateLimitPerToken <= 0 {
+		errs = append(errs, errors.New("RATE_LIMIT_PER_TOKEN deve ser maior que zero"))
+	}
+	if c.DefaultBlockTimeIP <= 0 {
+		errs = append(errs, errors.New("DEFAULT_BLOCK_TIME_IP deve ser maior que zero"))
+	}
+	if c.DefaultBlockTimeToken <= 0 {
+		errs = append(errs, errors.New("DEFAULT_BLOCK_TIME_TOKEN deve ser maior que zero"))
+	}
+	for _, penalty := range []struct {
+		name  string
+		steps []int
+		decay int
+	}{
+		{"IP", c.PenaltyStepsIP, c.PenaltyDecayIP},
+		{"TOKEN", c.PenaltyStepsToken, c.PenaltyDecayToken},
+	} {
+		if len(penalty.steps) == 0 {
+			continue
+		}
+		for _, step := range penalty.steps {
+			if step <= 0 {
+				errs = append(errs, fmt.Errorf("PENALTY_STEPS_%s: etapas devem ser maiores que zero", penalty.name))
+				break
+			}
+		}
+		if penalty.decay <= 0 {
+			errs = append(errs, fmt.Errorf("PENALTY_DECAY_%s deve ser maior que zero", penalty.name))
+		}
+	}
+	if len(c.Login.Paths) > 0 {
+		errs = append(errs, c.Login.validate()...)
+	}
+	switch c.TracingExporter {
+	case "", "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER inválido: %q", c.TracingExporter))
+	}
+	switch c.StorageBackend {
+	case "", "redis", "memory":
+	case "bolt":
+		if c.BoltCleanupSecs <= 0 {
+			errs = append(errs, errors.New("BOLT_CLEANUP_SECONDS deve ser maior que zero"))
+		}
+	case "gossip":
+		if c.Gossip.Token == "" && !IsLoopbackAddr(c.Gossip.Addr) {
+			errs = append(errs, errors.New("STORAGE_BACKEND=gossip exige GOSSIP_TOKEN quando GOSSIP_ADDR não é loopback"))
+		}
+	case "postgres":
+		if c.PostgresDSN == "" {
+			errs = append(errs, errors.New("STORAGE_BACKEND=postgres exige POSTGRES_DSN"))
+		}
+		if c.PostgresCleanupSecs <= 0 {
+			errs = append(errs, errors.New("POSTGRES_CLEANUP_SECONDS deve ser maior que zero"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_BACKEND inválido: %q (use redis, postgres, bolt, gossip ou memory)", c.StorageBackend))
+	}
+	switch c.RedisMode {
+	case "", "standalone":
+	case "sentinel":
+		if c.RedisMasterName == "" || len(c.RedisSentinelAddrs) == 0 {
+			errs = append(errs, errors.New("REDIS_MODE=sentinel exige REDIS_MASTER_NAME e REDIS_SENTINEL_ADDRS"))
+		}
+	case "cluster":
+		if len(c.RedisClusterAddrs) == 0 {
+			errs = append(errs, errors.New("REDIS_MODE=cluster exige REDIS_CLUSTER_ADDRS"))
+		}
+	case "sharded":
+		if len(c.RedisShardAddrs) == 0 {
+			errs = append(errs, errors.New("REDIS_MODE=sharded exige REDIS_SHARD_ADDRS"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("REDIS_MODE inválido: %q (use standalone, sentinel, cluster ou sharded)", c.RedisMode))
+	}
+	if !c.RedisTLS.Enabled && (c.RedisTLS.CAFile != "" || c.RedisTLS.CertFile != "") {
+		errs = append(errs, errors.New("REDIS_TLS_CA_FILE/REDIS_TLS_CERT_FILE exigem REDIS_TLS_ENABLED=true"))
+	}
+	if (c.RedisTLS.CertFile == "") != (c.RedisTLS.KeyFile == "") {
+		errs = append(errs, errors.New("REDIS_TLS_CERT_FILE e REDIS_TLS_KEY_FILE devem ser informados juntos"))
+	}
+	for key, mode := range map[string]string{"FAILURE_MODE_IP": c.FailureModeIP, "FAILURE_MODE_TOKEN": c.FailureModeToken} {
+		switch mode {
+		case "", "open", "closed", "local":
+		default:
+			errs = append(errs, fmt.Errorf("%s inválido: %q (use open, closed ou local)", key, mode))
+		}
+	}
+	for key, value := range map[string]string{"COUNTED_STATUSES_IP": c.CountedStatusesIP, "COUNTED_STATUSES_TOKEN": c.CountedStatusesToken} {
+		if err := validateStatusFilter(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// statusPattern aceita um código (304) ou uma classe (4xx), com "!" para excluir.
+var statusPattern = regexp.MustCompile(`^!?([1-5][0-9]{2}|[1-5][xX]{2})$`)
+
+func validateStatusFilter(input string) error {
+	for _, item := range strings.Split(input, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !statusPattern.MatchString(item) {
+			return fmt.Errorf("status inválido %q (use códigos como 304, classes como 4xx e ! para excluir)", item)
+		}
+	}
+	return nil
+}
+
+// IsLoopbackAddr indica se addr (host:porta) escuta apenas na interface de loopback.
+// Host vazio escuta em todas as interfaces.
+func IsLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (l LoginConfig) validate() []error {
+	var errs []error
+	if l.MaxFailuresPerIP < 0 || l.MaxFailuresPerUser < 0 || l.MaxFailuresPerUserIP < 0 {
+		errs = append(errs, errors.New("LOGIN_MAX_FAILURES_* não podem ser negativos"))
+	}
+	if l.MaxFailuresPerIP == 0 && l.MaxFailuresPerUser == 0 && l.MaxFailuresPerUserIP == 0 {
+		errs = append(errs, errors.New("LOGIN_PATHS exige ao menos um LOGIN_MAX_FAILURES_* maior que zero"))
+	}
+	if l.WindowSeconds <= 0 {
+		errs = append(errs, errors.New("LOGIN_FAILURE_WINDOW_SECONDS deve ser maior que zero"))
+	}
+	if l.BlockTimeSeconds <= 0 {
+		errs = append(errs, errors.New("LOGIN_BLOCK_TIME_SECONDS deve ser maior que zero"))
+	}
+	for _, status := range l.FailureStatuses {
+		if status < 100 || status > 599 {
+			errs = append(errs, fmt.Errorf("LOGIN_FAILURE_STATUSES: status HTTP inválido %d", status))
+		}
+	}
+	return errs
+}
+
 func parseBlockTimeList(input string) map[string]int {
 	result := make(map[string]int)
 	if input == "" {
@@ -62,17 +512,96 @@ func parseBlockTimeList(input string) map[string]int {
 	return result
 }
 
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
+func validateBlockTimeList(input string) error {
+	if input == "" {
+		return nil
+	}
+	for _, pair := range strings.Split(input, ";") {
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("entrada malformada %q (esperado chave=segundos)", pair)
+		}
+		if seconds, err := strconv.Atoi(parts[1]); err != nil || seconds <= 0 {
+			return fmt.Errorf("tempo de bloqueio inválido em %q", pair)
+		}
+	}
+	return nil
+}
+
+func getEnv(lookup lookupFunc, key, fallback string) string {
+	if value, exists := lookup(key); exists {
 		return value
 	}
 	return fallback
 }
 
-func getEnvAsInt(key string, fallback int) int {
-	valueStr := getEnv(key, "")
+func getEnvAsInt(lookup lookupFunc, key string, fallback int) int {
+	valueStr := getEnv(lookup, key, "")
 	if value, err := strconv.Atoi(valueStr); err == nil {
 		return value
 	}
 	return fallback
 }
+
+func getEnvAsBool(lookup lookupFunc, key string, fallback bool) bool {
+	valueStr := getEnv(lookup, key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// RedisAddrs devolve os endereços relevantes para o modo configurado.
+func (c Config) RedisAddrs() []string {
+	switch c.RedisMode {
+	case "sentinel":
+		return c.RedisSentinelAddrs
+	case "cluster":
+		return c.RedisClusterAddrs
+	case "sharded":
+		return c.RedisShardAddrs
+	default:
+		if c.RedisAddr == "" {
+			return nil
+		}
+		return []string{c.RedisAddr}
+	}
+}
+
+// SetRedisAddrs substitui os endereços do modo configurado; é o inverso de RedisAddrs.
+func (c *Config) SetRedisAddrs(addrs []string) {
+	switch c.RedisMode {
+	case "sentinel":
+		c.RedisSentinelAddrs = addrs
+	case "cluster":
+		c.RedisClusterAddrs = addrs
+	case "sharded":
+		c.RedisShardAddrs = addrs
+	default:
+		c.RedisAddr = ""
+		if len(addrs) > 0 {
+			c.RedisAddr = addrs[0]
+		}
+	}
+}
+
+// getEnvAsIntList ignora itens que não são inteiros; o ValidateFile os aponta.
+func getEnvAsIntList(lookup lookupFunc, key string) []int {
+	var result []int
+	for _, item := range getEnvAsList(lookup, key) {
+		if value, err := strconv.Atoi(item); err == nil {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func getEnvAsList(lookup lookupFunc, key string) []string {
+	var result []string
+	for _, item := range strings.Split(getEnv(lookup, key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,6 +17,8 @@ func TestLoadConfig(t *testing.T) {
 	os.Setenv("BLOCK_TIME_PER_IP", "192.168.1.1=120;192.168.1.2=600")
 	os.Setenv("BLOCK_TIME_PER_TOKEN", "token123=180;tokenABC=900")
 	os.Setenv("REDIS_ADDR", "redis-test:6379")
+	os.Setenv("REDIS_PASSWORD", "s3cr3t")
+	os.Setenv("REDIS_POOL_SIZE", "50")
 	os.Setenv("SERVER_PORT", "9090")
 
 	// Carregar configurações
@@ -27,8 +30,15 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, 180, Cfg.DefaultBlockTimeIP)
 	assert.Equal(t, 360, Cfg.DefaultBlockTimeToken)
 	assert.Equal(t, "redis-test:6379", Cfg.RedisAddr)
+	assert.Equal(t, "s3cr3t", Cfg.RedisPassword)
+	assert.Equal(t, 50, Cfg.RedisPool.Size)
+	assert.False(t, Cfg.RedisTLS.Enabled)
 	assert.Equal(t, "9090", Cfg.ServerPort)
 
+	// Circuit breaker desligado por padrão
+	assert.False(t, Cfg.CircuitBreaker.Enabled)
+	assert.Equal(t, 5, Cfg.CircuitBreaker.FailureThreshold)
+
 	// Testando tempos de bloqueio individuais
 	assert.Equal(t, 120, Cfg.BlockTimePerIP["192.168.1.1"])
 	assert.Equal(t, 600, Cfg.BlockTimePerIP["192.168.1.2"])
@@ -55,3 +65,184 @@ func GetBlockTimeForToken(token string) int {
 	}
 	return Cfg.DefaultBlockTimeToken
 }
+
+func TestLoadConfig_RedisModes(t *testing.T) {
+	os.Setenv("REDIS_MODE", "sentinel")
+	os.Setenv("REDIS_MASTER_NAME", "mymaster")
+	os.Setenv("REDIS_SENTINEL_ADDRS", "sentinel-1:26379, sentinel-2:26379")
+	defer os.Unsetenv("REDIS_MODE")
+	defer os.Unsetenv("REDIS_MASTER_NAME")
+	defer os.Unsetenv("REDIS_SENTINEL_ADDRS")
+
+	LoadConfig()
+
+	assert.Equal(t, []string{"sentinel-1:26379", "sentinel-2:26379"}, Cfg.RedisAddrs())
+	assert.NoError(t, Cfg.Validate())
+
+	Cfg.RedisMasterName = ""
+	assert.ErrorContains(t, Cfg.Validate(), "REDIS_MASTER_NAME")
+
+	Cfg.RedisMode = "cluster"
+	assert.ErrorContains(t, Cfg.Validate(), "REDIS_CLUSTER_ADDRS")
+
+	Cfg.RedisMode = "standalone"
+	Cfg.RedisTLS = RedisTLSConfig{CAFile: "/etc/redis/ca.pem", CertFile: "/etc/redis/client.pem"}
+	assert.ErrorContains(t, Cfg.Validate(), "REDIS_TLS_ENABLED")
+	assert.ErrorContains(t, Cfg.Validate(), "REDIS_TLS_KEY_FILE")
+}
+
+func TestValidateFile(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.env")
+	os.WriteFile(valid, []byte("RATE_LIMIT_PER_IP=10\nBLOCK_TIME_PER_IP=192.168.1.1=120\nTRACING_EXPORTER=stdout\n"), 0o600)
+
+	cfg, err := ValidateFile(valid)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, cfg.RateLimitPerIP)
+	assert.Equal(t, 120, cfg.BlockTimePerIP["192.168.1.1"])
+
+	// O arquivo não deve alterar o ambiente do processo
+	_, exists := os.LookupEnv("TRACING_EXPORTER")
+	assert.False(t, exists)
+
+	invalid := filepath.Join(dir, "invalid.env")
+	os.WriteFile(invalid, []byte("RATE_LIMIT_PER_IP=dez\nRATE_LIMIT_PER_TOKEN=0\nBLOCK_TIME_PER_TOKEN=token1=abc\nTRACING_EXPORTER=zipkin\nFAILURE_MODE_IP=panic\n"), 0o600)
+
+	_, err = ValidateFile(invalid)
+	assert.ErrorContains(t, err, "RATE_LIMIT_PER_IP")
+	assert.ErrorContains(t, err, "RATE_LIMIT_PER_TOKEN deve ser maior que zero")
+	assert.ErrorContains(t, err, "BLOCK_TIME_PER_TOKEN")
+	assert.ErrorContains(t, err, "TRACING_EXPORTER")
+	assert.ErrorContains(t, err, "FAILURE_MODE_IP")
+
+	_, err = ValidateFile(filepath.Join(dir, "inexistente.env"))
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_StorageBackend(t *testing.T) {
+	os.Setenv("STORAGE_BACKEND", "postgres")
+	defer os.Unsetenv("STORAGE_BACKEND")
+
+	LoadConfig()
+
+	assert.Equal(t, "postgres", Cfg.StorageBackend)
+	assert.Equal(t, 60, Cfg.PostgresCleanupSecs)
+	assert.ErrorContains(t, Cfg.Validate(), "POSTGRES_DSN")
+
+	Cfg.PostgresDSN = "postgres://rate_limiter@localhost/rate_limiter"
+	assert.NoError(t, Cfg.Validate())
+
+	// Intervalo zero faria o time.NewTicker da limpeza entrar em pânico
+	Cfg.PostgresCleanupSecs = 0
+	assert.ErrorContains(t, Cfg.Validate(), "POSTGRES_CLEANUP_SECONDS")
+	Cfg.PostgresCleanupSecs = 60
+
+	Cfg.StorageBackend = "bolt"
+	Cfg.BoltCleanupSecs = -1
+	assert.ErrorContains(t, Cfg.Validate(), "BOLT_CLEANUP_SECONDS")
+	Cfg.BoltCleanupSecs = 60
+	assert.NoError(t, Cfg.Validate())
+
+	// Gossip sem token só pode escutar em loopback
+	Cfg.StorageBackend = "gossip"
+	assert.ErrorContains(t, Cfg.Validate(), "GOSSIP_TOKEN")
+	Cfg.Gossip.Addr = "127.0.0.1:7946"
+	assert.NoError(t, Cfg.Validate())
+
+	assert.True(t, IsLoopbackAddr("[::1]:7946"))
+	assert.True(t, IsLoopbackAddr("localhost:7946"))
+	assert.False(t, IsLoopbackAddr("10.0.0.2:7946"))
+	Cfg.Gossip.Addr, Cfg.Gossip.Token = ":7946", "segredo"
+	assert.NoError(t, Cfg.Validate())
+
+	Cfg.StorageBackend = "mongo"
+	assert.ErrorContains(t, Cfg.Validate(), "STORAGE_BACKEND")
+}
+
+func TestLoadConfig_PenaltySteps(t *testing.T) {
+	os.Setenv("PENALTY_STEPS_IP", "60, 300,1800,86400")
+	defer os.Unsetenv("PENALTY_STEPS_IP")
+
+	LoadConfig()
+
+	assert.Equal(t, []int{60, 300, 1800, 86400}, Cfg.PenaltyStepsIP)
+	assert.Equal(t, 86400, Cfg.PenaltyDecayIP)
+	assert.Empty(t, Cfg.PenaltyStepsToken)
+	assert.NoError(t, Cfg.Validate())
+
+	Cfg.PenaltyStepsIP = []int{60, 0}
+	Cfg.PenaltyDecayIP = 0
+	assert.ErrorContains(t, Cfg.Validate(), "PENALTY_STEPS_IP")
+	assert.ErrorContains(t, Cfg.Validate(), "PENALTY_DECAY_IP")
+
+	// Sem etapas o decaimento não importa
+	Cfg.PenaltyStepsIP = nil
+	assert.NoError(t, Cfg.Validate())
+
+	path := filepath.Join(t.TempDir(), "penalty.env")
+	os.WriteFile(path, []byte("PENALTY_STEPS_TOKEN=60,5m\n"), 0o600)
+	_, err := ValidateFile(path)
+	assert.ErrorContains(t, err, "PENALTY_STEPS_TOKEN")
+}
+
+func TestLoadConfig_Login(t *testing.T) {
+	os.Setenv("LOGIN_PATHS", "/login, /auth/token")
+	os.Setenv("LOGIN_FAILURE_STATUSES", "401,403")
+	defer os.Unsetenv("LOGIN_PATHS")
+	defer os.Unsetenv("LOGIN_FAILURE_STATUSES")
+
+	LoadConfig()
+
+	assert.Equal(t, []string{"/login", "/auth/token"}, Cfg.Login.Paths)
+	assert.Equal(t, "username", Cfg.Login.UsernameField)
+	assert.Equal(t, []int{401, 403}, Cfg.Login.FailureStatuses)
+	assert.Equal(t, 5, Cfg.Login.MaxFailuresPerUserIP)
+	assert.NoError(t, Cfg.Validate())
+
+	Cfg.Login.WindowSeconds = 0
+	Cfg.Login.FailureStatuses = []int{4010}
+	assert.ErrorContains(t, Cfg.Validate(), "LOGIN_FAILURE_WINDOW_SECONDS")
+	assert.ErrorContains(t, Cfg.Validate(), "LOGIN_FAILURE_STATUSES")
+
+	Cfg.Login = LoginConfig{Paths: []string{"/login"}, WindowSeconds: 900, BlockTimeSeconds: 900}
+	assert.ErrorContains(t, Cfg.Validate(), "LOGIN_MAX_FAILURES_")
+
+	// Sem rotas protegidas o restante não é validado
+	Cfg.Login.Paths = nil
+	assert.NoError(t, Cfg.Validate())
+}
+
+func TestLoadConfig_CountedStatuses(t *testing.T) {
+	os.Setenv("COUNTED_STATUSES_IP", "4xx")
+	os.Setenv("COUNTED_STATUSES_TOKEN", "!304, !5xx")
+	defer os.Unsetenv("COUNTED_STATUSES_IP")
+	defer os.Unsetenv("COUNTED_STATUSES_TOKEN")
+
+	LoadConfig()
+
+	assert.Equal(t, "4xx", Cfg.CountedStatusesIP)
+	assert.Equal(t, "!304, !5xx", Cfg.CountedStatusesToken)
+	assert.NoError(t, Cfg.Validate())
+
+	Cfg.CountedStatusesIP = "4xx,6xx"
+	assert.ErrorContains(t, Cfg.Validate(), "COUNTED_STATUSES_IP")
+
+	Cfg.CountedStatusesIP = "40"
+	assert.ErrorContains(t, Cfg.Validate(), "COUNTED_STATUSES_IP")
+}
+
+func TestLoadConfig_RedisShards(t *testing.T) {
+	os.Setenv("REDIS_MODE", "sharded")
+	os.Setenv("REDIS_SHARD_ADDRS", "redis-a:6379,redis-b:6379")
+	defer os.Unsetenv("REDIS_MODE")
+	defer os.Unsetenv("REDIS_SHARD_ADDRS")
+
+	LoadConfig()
+
+	assert.Equal(t, []string{"redis-a:6379", "redis-b:6379"}, Cfg.RedisAddrs())
+	assert.NoError(t, Cfg.Validate())
+
+	Cfg.RedisShardAddrs = nil
+	assert.ErrorContains(t, Cfg.Validate(), "REDIS_SHARD_ADDRS")
+}
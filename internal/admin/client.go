@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"rate-limiter/internal/storage"
+	"strings"
+	"time"
+)
+
+// Client consome a API administrativa de uma instância em execução. Os nomes
+// dos métodos espelham o RateLimiterStorage para que ferramentas possam usar
+// qualquer um dos dois de forma intercambiável.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	var body struct {
+		Blocked []string `json:"blocked"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/admin/blocks", nil, &body); err != nil {
+		return nil, err
+	}
+	return body.Blocked, nil
+}
+
+func (c *Client) InspectKey(ctx context.Context, key string) (storage.KeyInfo, error) {
+	var body KeyResponse
+	if err := c.do(ctx, http.MethodGet, "/admin/keys/"+url.PathEscape(key), nil, &body); err != nil {
+		return storage.KeyInfo{}, err
+	}
+	return storage.KeyInfo{
+		Key:            body.Key,
+		Count:          body.Count,
+		CountTTL:       time.Duration(body.CountTTLSeconds * float64(time.Second)),
+		Blocked:        body.Blocked,
+		BlockRemaining: time.Duration(body.BlockRemainingSeconds * float64(time.Second)),
+	}, nil
+}
+
+func (c *Client) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	return c.do(ctx, http.MethodPost, "/admin/blocks/"+url.PathEscape(key), BlockRequest{DurationSeconds: int(duration.Seconds())}, nil)
+}
+
+func (c *Client) UnblockKey(ctx context.Context, key string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/blocks/"+url.PathEscape(key), nil, nil)
+}
+
+func (c *Client) ResetKey(ctx context.Context, key string) error {
+	return c.do(ctx, http.MethodDelete, "/admin/keys/"+url.PathEscape(key), nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var errBody struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		return fmt.Errorf("admin API respondeu %d: %s", resp.StatusCode, errBody.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
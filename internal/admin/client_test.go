@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	router, memStorage := setupTestAdmin()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := NewClient(server.URL, testToken)
+
+	err := client.BlockKey(ctx, "192.168.1.1", time.Minute)
+	assert.NoError(t, err)
+
+	keys, err := client.ListBlockedKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.168.1.1"}, keys)
+
+	memStorage.IncrementRequest(ctx, "192.168.1.1")
+	info, err := client.InspectKey(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.Count)
+	assert.True(t, info.Blocked)
+
+	assert.NoError(t, client.UnblockKey(ctx, "192.168.1.1"))
+	assert.NoError(t, client.ResetKey(ctx, "192.168.1.1"))
+
+	info, err = client.InspectKey(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.False(t, info.Blocked)
+	assert.Equal(t, 0, info.Count)
+}
+
+func TestClient_Unauthorized(t *testing.T) {
+	ctx := context.Background()
+	router, _ := setupTestAdmin()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "token-errado").ListBlockedKeys(ctx)
+	assert.ErrorContains(t, err, "401")
+}
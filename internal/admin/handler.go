@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"rate-limiter/internal/storage"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	storage  storage.RateLimiterStorage
+	logger   *zap.Logger
+	snapshot SnapshotFunc
+}
+
+// SnapshotFunc grava o estado do storage sob demanda (ex.: memória em arquivo).
+type SnapshotFunc func() error
+
+type KeyResponse struct {
+	Key                   string  `json:"key"`
+	Count                 int     `json:"count"`
+	CountTTLSeconds       float64 `json:"count_ttl_seconds"`
+	Blocked               bool    `json:"blocked"`
+	BlockRemainingSeconds float64 `json:"block_remaining_seconds"`
+}
+
+type BlockRequest struct {
+	DurationSeconds int `json:"duration_seconds" binding:"required,gt=0"`
+}
+
+func NewHandler(storage storage.RateLimiterStorage, logger *zap.Logger) *Handler {
+	return &Handler{storage: storage, logger: logger}
+}
+
+// WithSnapshot habilita POST /snapshot; sem ele a rota responde 501.
+func (h *Handler) WithSnapshot(snapshot SnapshotFunc) *Handler {
+	h.snapshot = snapshot
+	return h
+}
+
+func (h *Handler) Register(group *gin.RouterGroup) {
+	group.GET("/blocks", h.listBlocked)
+	group.POST("/blocks/:key", h.block)
+	group.DELETE("/blocks/:key", h.unblock)
+	group.GET("/keys/:key", h.inspect)
+	group.DELETE("/keys/:key", h.reset)
+	group.POST("/snapshot", h.takeSnapshot)
+}
+
+// AuthMiddleware exige o cabeçalho "Authorization: Bearer <token>".
+func AuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Sem o esquema Bearer o cabeçalho é recusado, mesmo que traga o token puro
+		provided, hasScheme := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || !hasScheme || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (h *Handler) listBlocked(c *gin.Context) {
+	keys, err := h.storage.ListBlockedKeys(c.Request.Context())
+	if err != nil {
+		h.fail(c, "Erro ao listar chaves bloqueadas", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"blocked": keys})
+}
+
+func (h *Handler) inspect(c *gin.Context) {
+	info, err := h.storage.InspectKey(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		h.fail(c, "Erro ao inspecionar chave", err)
+		return
+	}
+	c.JSON(http.StatusOK, NewKeyResponse(info))
+}
+
+func (h *Handler) block(c *gin.Context) {
+	var req BlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	key := c.Param("key")
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if err := h.storage.BlockKey(c.Request.Context(), key, duration); err != nil {
+		h.fail(c, "Erro ao bloquear chave", err)
+		return
+	}
+	h.logger.Info("Chave bloqueada manualmente", zap.String("key", key), zap.Duration("duration", duration))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) unblock(c *gin.Context) {
+	key := c.Param("key")
+	if err := h.storage.UnblockKey(c.Request.Context(), key); err != nil {
+		h.fail(c, "Erro ao desbloquear chave", err)
+		return
+	}
+	h.logger.Info("Chave desbloqueada manualmente", zap.String("key", key))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) reset(c *gin.Context) {
+	key := c.Param("key")
+	if err := h.storage.ResetKey(c.Request.Context(), key); err != nil {
+		h.fail(c, "Erro ao resetar contador", err)
+		return
+	}
+	h.logger.Info("Contador resetado manualmente", zap.String("key", key))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) takeSnapshot(c *gin.Context) {
+	if h.snapshot == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"message": "Snapshot disponível apenas para o armazenamento em memória com SNAPSHOT_PATH"})
+		return
+	}
+	if err := h.snapshot(); err != nil {
+		h.fail(c, "Erro ao gravar snapshot", err)
+		return
+	}
+	h.logger.Info("Snapshot gravado manualmente")
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) fail(c *gin.Context, message string, err error) {
+	h.logger.Error(message, zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+}
+
+func NewKeyResponse(info storage.KeyInfo) KeyResponse {
+	return KeyResponse{
+		Key:                   info.Key,
+		Count:                 info.Count,
+		CountTTLSeconds:       info.CountTTL.Seconds(),
+		Blocked:               info.Blocked,
+		BlockRemainingSeconds: info.BlockRemaining.Seconds(),
+	}
+}
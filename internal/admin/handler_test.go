@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rate-limiter/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+const testToken = "admin-secret"
+
+func setupTestAdmin() (*gin.Engine, *storage.MemoryRateLimiterStorage) {
+	gin.SetMode(gin.TestMode)
+
+	memStorage := storage.NewMemoryStorage()
+	router := gin.New()
+	NewHandler(memStorage, zap.NewNop()).Register(router.Group("/admin", AuthMiddleware(testToken)))
+
+	return router, memStorage
+}
+
+func doRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdmin_RequiresToken(t *testing.T) {
+	router, _ := setupTestAdmin()
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/blocks", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req.Header.Set("Authorization", "Bearer outro-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// O token certo sem o esquema Bearer também é recusado
+	req.Header.Set("Authorization", testToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAdmin_EmptyTokenDisablesAccess(t *testing.T) {
+	router := gin.New()
+	router.Use(AuthMiddleware(""))
+	router.GET("/admin/blocks", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/blocks", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAdmin_BlockListAndUnblock(t *testing.T) {
+	ctx := context.Background()
+	router, memStorage := setupTestAdmin()
+
+	w := doRequest(router, http.MethodPost, "/admin/blocks/192.168.1.1", `{"duration_seconds": 60}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	blocked, _ := memStorage.IsBlocked(ctx, "192.168.1.1")
+	assert.True(t, blocked)
+
+	w = doRequest(router, http.MethodGet, "/admin/blocks", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"blocked": ["192.168.1.1"]}`, w.Body.String())
+
+	w = doRequest(router, http.MethodDelete, "/admin/blocks/192.168.1.1", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	blocked, _ = memStorage.IsBlocked(ctx, "192.168.1.1")
+	assert.False(t, blocked)
+}
+
+func TestAdmin_BlockRejectsInvalidDuration(t *testing.T) {
+	router, _ := setupTestAdmin()
+
+	w := doRequest(router, http.MethodPost, "/admin/blocks/192.168.1.1", `{"duration_seconds": 0}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdmin_InspectAndReset(t *testing.T) {
+	ctx := context.Background()
+	router, memStorage := setupTestAdmin()
+
+	memStorage.IncrementRequest(ctx, "token123")
+	memStorage.IncrementRequest(ctx, "token123")
+	memStorage.BlockKey(ctx, "token123", time.Minute)
+
+	w := doRequest(router, http.MethodGet, "/admin/keys/token123", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var info KeyResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "token123", info.Key)
+	assert.Equal(t, 2, info.Count)
+	assert.True(t, info.Blocked)
+	assert.InDelta(t, 60, info.BlockRemainingSeconds, 1)
+
+	w = doRequest(router, http.MethodDelete, "/admin/keys/token123", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	count, _ := memStorage.GetRequestCount(ctx, "token123")
+	assert.Equal(t, 0, count)
+}
+
+func TestAdmin_Snapshot(t *testing.T) {
+	router, _ := setupTestAdmin()
+
+	// Sem snapshot configurado a rota existe mas não faz nada
+	w := doRequest(router, http.MethodPost, "/admin/snapshot", "")
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	router = gin.New()
+	NewHandler(storage.NewMemoryStorage(), zap.NewNop()).
+		WithSnapshot(func() error { calls++; return nil }).
+		Register(router.Group("/admin", AuthMiddleware(testToken)))
+
+	w = doRequest(router, http.MethodPost, "/admin/snapshot", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 1, calls)
+}
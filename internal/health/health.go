@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"rate-limiter/internal/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+type Checker struct {
+	storage         storage.RateLimiterStorage
+	degradedLatency time.Duration
+	fallback        func() bool
+}
+
+type Report struct {
+	Status         string  `json:"status"`
+	Storage        string  `json:"storage"`
+	StorageLatency float64 `json:"storage_latency_ms"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// NewChecker cria o verificador de saúde. Respostas do storage mais lentas que
+// degradedLatency são reportadas como "degraded" sem tirar a instância do ar.
+func NewChecker(storage storage.RateLimiterStorage, degradedLatency time.Duration) *Checker {
+	return &Checker{storage: storage, degradedLatency: degradedLatency}
+}
+
+// WithFallback informa se a instância continua atendendo sem o storage (circuito
+// servindo do fallback ou FAILURE_MODE open/local). Nesse caso uma falha no Ping
+// vira "degraded" em vez de tirar todas as réplicas do balanceador ao mesmo tempo.
+func (h *Checker) WithFallback(serving func() bool) *Checker {
+	h.fallback = serving
+	return h
+}
+
+func (h *Checker) Register(r gin.IRoutes) {
+	r.GET("/healthz", h.liveness)
+	r.GET("/readyz", h.readiness)
+}
+
+func (h *Checker) Check(ctx context.Context) Report {
+	start := time.Now()
+	err := h.storage.Ping(ctx)
+	elapsed := time.Since(start)
+
+	report := Report{
+		Status:         StatusOK,
+		Storage:        StatusOK,
+		StorageLatency: float64(elapsed.Microseconds()) / 1000,
+	}
+	switch {
+	case err != nil:
+		report.Status = StatusDown
+		report.Storage = StatusDown
+		report.Error = err.Error()
+		if h.fallback != nil && h.fallback() {
+			report.Status = StatusDegraded
+		}
+	case h.degradedLatency > 0 && elapsed > h.degradedLatency:
+		report.Status = StatusDegraded
+		report.Storage = StatusDegraded
+	}
+	return report
+}
+
+// liveness só indica que o processo responde: reiniciar a instância não
+// resolve uma queda do Redis, então o estado do storage é apenas informativo.
+func (h *Checker) liveness(c *gin.Context) {
+	report := h.Check(c.Request.Context())
+	if report.Status == StatusDown {
+		report.Status = StatusDegraded
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Checker) readiness(c *gin.Context) {
+	report := h.Check(c.Request.Context())
+	if report.Status == StatusDown {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,136 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"rate-limiter/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// pingStorage simula um storage cujo Ping pode falhar ou demorar
+type pingStorage struct {
+	*storage.MemoryRateLimiterStorage
+	err   error
+	delay time.Duration
+}
+
+func (p *pingStorage) Ping(ctx context.Context) error {
+	time.Sleep(p.delay)
+	return p.err
+}
+
+func (p *pingStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	return p.MemoryRateLimiterStorage.IncrementRequest(ctx, key)
+}
+
+func serve(checker *Checker, path string) (int, Report) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	checker.Register(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report Report
+	json.Unmarshal(w.Body.Bytes(), &report)
+	return w.Code, report
+}
+
+func TestHealth_StorageUp(t *testing.T) {
+	checker := NewChecker(storage.NewMemoryStorage(), time.Second)
+
+	code, report := serve(checker, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Storage)
+
+	code, _ = serve(checker, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestHealth_StorageDown(t *testing.T) {
+	checker := NewChecker(&pingStorage{
+		MemoryRateLimiterStorage: storage.NewMemoryStorage(),
+		err:                      errors.New("connection refused"),
+	}, time.Second)
+
+	code, report := serve(checker, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusDown, report.Storage)
+	assert.Equal(t, "connection refused", report.Error)
+
+	// A liveness continua respondendo 200 para não reiniciar a instância
+	code, report = serve(checker, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusDown, report.Storage)
+}
+
+func TestHealth_StorageSlow(t *testing.T) {
+	checker := NewChecker(&pingStorage{
+		MemoryRateLimiterStorage: storage.NewMemoryStorage(),
+		delay:                    20 * time.Millisecond,
+	}, 5*time.Millisecond)
+
+	code, report := serve(checker, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.GreaterOrEqual(t, report.StorageLatency, 20.0)
+}
+
+func TestHealth_StorageDownWithFallback(t *testing.T) {
+	down := &pingStorage{
+		MemoryRateLimiterStorage: storage.NewMemoryStorage(),
+		err:                      errors.New("connection refused"),
+	}
+
+	// FAILURE_MODE open/local: o limiter continua decidindo sem o storage
+	checker := NewChecker(down, time.Second).WithFallback(func() bool { return true })
+	code, report := serve(checker, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusDown, report.Storage)
+	assert.Equal(t, "connection refused", report.Error)
+
+	// FAILURE_MODE closed sem fallback servindo: a instância sai do balanceador
+	checker = NewChecker(down, time.Second).WithFallback(func() bool { return false })
+	code, report = serve(checker, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusDown, report.Status)
+}
+
+func TestHealth_CircuitBreakerServingFallback(t *testing.T) {
+	down := &pingStorage{
+		MemoryRateLimiterStorage: storage.NewMemoryStorage(),
+		err:                      errors.New("connection refused"),
+	}
+	breaker := storage.NewCircuitBreakerStorage(down, storage.NewMemoryStorage(), storage.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Hour,
+	})
+	checker := NewChecker(breaker, time.Second).WithFallback(func() bool {
+		return breaker.State() != storage.CircuitClosed
+	})
+
+	// Com o circuito ainda fechado o Ping falho tira a instância do ar
+	code, _ := serve(checker, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	// Aberto, o fallback em memória atende e a instância fica apenas degradada
+	_, err := breaker.IncrementRequest(context.Background(), "192.168.1.1")
+	assert.Error(t, err)
+	code, report := serve(checker, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusDegraded, report.Status)
+}
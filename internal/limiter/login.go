@@ -0,0 +1,174 @@
+package limiter
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"rate-limiter/internal/storage"
+	"rate-limiter/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	dimensionLoginIP     = "login_ip"
+	dimensionLoginUser   = "login_user"
+	dimensionLoginUserIP = "login_user_ip"
+
+	loginKeyPrefix = "login:"
+)
+
+// loginCounter é uma das dimensões contadas nas falhas de login.
+type loginCounter struct {
+	dimension string
+	key       string
+	limit     int
+}
+
+// CheckLogin verifica, antes do handler, se o IP, o usuário ou o par usuário+IP está
+// bloqueado por excesso de falhas. A tentativa em si só é contada por RecordLoginFailure.
+func (rl *RateLimiterService) CheckLogin(ctx context.Context, ip, username string) (RateLimitResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "RateLimiterService.CheckLogin")
+	defer span.End()
+
+	return rl.runLogin(ctx, span, ip, username, rl.checkLogin)
+}
+
+// RecordLoginFailure conta uma tentativa de login que falhou e bloqueia as dimensões
+// que atingiram o limite. O resultado indica se a próxima tentativa será rejeitada.
+func (rl *RateLimiterService) RecordLoginFailure(ctx context.Context, ip, username string) (RateLimitResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "RateLimiterService.RecordLoginFailure")
+	defer span.End()
+
+	return rl.runLogin(ctx, span, ip, username, rl.recordLoginFailure)
+}
+
+// runLogin executa a verificação ou a contagem no storage e, se ele falhar, aplica o
+// modo de falha da política de IP, já que toda tentativa de login tem um IP.
+func (rl *RateLimiterService) runLogin(ctx context.Context, span trace.Span, ip, username string,
+	run func(context.Context, trace.Span, storage.RateLimiterStorage, []loginCounter) (RateLimitResult, error)) (RateLimitResult, error) {
+	counters := rl.loginCounters(ip, username)
+
+	result, err := run(ctx, span, rl.storage, counters)
+	if err != nil && ctx.Err() != nil {
+		return RateLimitResult{}, ctx.Err()
+	}
+	if err != nil {
+		mode := rl.config.FailureModeIP
+		rl.logger.Error("Falha no storage ao verificar tentativas de login",
+			zap.String("ip", ip),
+			zap.String("username", username),
+			zap.String("failure_mode", string(mode)),
+			zap.Error(err),
+		)
+		rl.metrics.ObserveStorageFailure(dimensionLoginIP, string(mode))
+		span.SetAttributes(attribute.String("rate_limiter.failure_mode", string(mode)))
+
+		switch mode {
+		case FailClosed:
+			result = RateLimitResult{Allowed: false, BlockTime: rl.config.Login.BlockTime, FailureMode: FailClosed}
+		case FailLocal:
+			result, _ = run(ctx, span, rl.fallback, counters)
+			result.FailureMode = FailLocal
+		default:
+			result = RateLimitResult{Allowed: true, FailureMode: FailOpen}
+		}
+	}
+
+	span.SetAttributes(attribute.Bool("rate_limiter.allowed", result.Allowed))
+	return result, err
+}
+
+func (rl *RateLimiterService) checkLogin(ctx context.Context, span trace.Span, store storage.RateLimiterStorage, counters []loginCounter) (RateLimitResult, error) {
+	result := RateLimitResult{Allowed: true}
+	for _, counter := range counters {
+		var blocked bool
+		err := rl.traceStorage(ctx, "IsBlocked", func(ctx context.Context) (err error) {
+			blocked, err = store.IsBlocked(ctx, counter.key)
+			return err
+		})
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		rl.metrics.ObserveDecision(counter.dimension, policyDefault, !blocked)
+		if !blocked {
+			continue
+		}
+
+		var blockTime time.Duration
+		err = rl.traceStorage(ctx, "GetBlockRemaining", func(ctx context.Context) (err error) {
+			blockTime, err = store.GetBlockRemaining(ctx, counter.key)
+			return err
+		})
+		if err != nil {
+			rl.logger.Error("Erro ao obter tempo restante do bloqueio", zap.String("key", counter.key), zap.Error(err))
+			blockTime = rl.config.Login.BlockTime
+		}
+		rl.logger.Warn("Tentativa de login bloqueada", zap.String(counter.dimension, counter.key), zap.Duration("block_time", blockTime))
+		span.SetAttributes(attribute.String("rate_limiter.dimension", counter.dimension))
+
+		// Vale o bloqueio mais longo entre as dimensões
+		result.Allowed = false
+		result.BlockTime = max(result.BlockTime, blockTime)
+	}
+	return result, nil
+}
+
+func (rl *RateLimiterService) recordLoginFailure(ctx context.Context, span trace.Span, store storage.RateLimiterStorage, counters []loginCounter) (RateLimitResult, error) {
+	policy := rl.config.Login
+	result := RateLimitResult{Allowed: true}
+	for _, counter := range counters {
+		// As falhas usam o contador de reincidências: ele sobrevive à janela de um
+		// minuto dos contadores de requisições e decai Window após a última falha
+		var failures int
+		err := rl.traceStorage(ctx, "IncrementStrikes", func(ctx context.Context) (err error) {
+			failures, err = store.IncrementStrikes(ctx, counter.key, policy.Window)
+			return err
+		})
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		span.SetAttributes(attribute.Int("rate_limiter."+counter.dimension+".failures", failures))
+		if failures < counter.limit {
+			continue
+		}
+
+		err = rl.traceStorage(ctx, "BlockKey", func(ctx context.Context) error {
+			return store.BlockKey(ctx, counter.key, policy.BlockTime)
+		})
+		if err != nil {
+			rl.logger.Error("Erro ao bloquear chave", zap.String("key", counter.key), zap.Error(err))
+		}
+		rl.logger.Warn("Tentativas de login esgotadas", zap.String(counter.dimension, counter.key), zap.Int("failures", failures), zap.Duration("block_time", policy.BlockTime))
+
+		result.Allowed = false
+		result.BlockTime = policy.BlockTime
+	}
+	return result, nil
+}
+
+// loginCounters monta as chaves de cada dimensão. O usuário é normalizado para que
+// variações de caixa e espaços não abram contadores novos; sem usuário, só o IP conta.
+func (rl *RateLimiterService) loginCounters(ip, username string) []loginCounter {
+	policy := rl.config.Login
+	username = strings.ToLower(strings.TrimSpace(username))
+
+	candidates := []loginCounter{{dimensionLoginIP, loginKeyPrefix + "ip:" + ip, policy.MaxFailuresPerIP}}
+	if username != "" {
+		candidates = append(candidates,
+			loginCounter{dimensionLoginUser, loginKeyPrefix + "user:" + username, policy.MaxFailuresPerUser},
+			loginCounter{dimensionLoginUserIP, loginKeyPrefix + "user_ip:" + username + "|" + ip, policy.MaxFailuresPerUserIP},
+		)
+	}
+
+	counters := candidates[:0]
+	for _, counter := range candidates {
+		if counter.limit > 0 {
+			counters = append(counters, counter)
+		}
+	}
+	return counters
+}
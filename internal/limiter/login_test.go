@@ -0,0 +1,263 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"rate-limiter/config"
+	"rate-limiter/internal/storage"
+	"rate-limiter/internal/storage/storagetest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupLoginRateLimiter() (*RateLimiterService, *storagetest.Clock) {
+	config.InitLogger()
+
+	clock := storagetest.NewClock()
+	memStorage := storage.NewMemoryStorageWithOptions(storage.MemoryOptions{Clock: clock})
+
+	return NewRateLimiterService(memStorage, RateLimiterConfig{
+		RateLimitPerIP:        100,
+		RateLimitPerToken:     100,
+		DefaultBlockTimeIP:    60,
+		DefaultBlockTimeToken: 60,
+		Login: LoginPolicy{
+			Paths:                []string{"/login"},
+			MaxFailuresPerIP:     10,
+			MaxFailuresPerUser:   5,
+			MaxFailuresPerUserIP: 3,
+			Window:               15 * time.Minute,
+			BlockTime:            10 * time.Minute,
+		},
+	}, config.Logger).WithClock(clock), clock
+}
+
+func failLogins(limiter *RateLimiterService, ip, username string, attempts int) {
+	for i := 0; i < attempts; i++ {
+		limiter.RecordLoginFailure(context.Background(), ip, username)
+	}
+}
+
+func TestLogin_BlocksUserIPPair(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := setupLoginRateLimiter()
+
+	failLogins(limiter, "10.0.0.1", "alice", 2)
+	result, _ := limiter.CheckLogin(ctx, "10.0.0.1", "alice")
+	assert.True(t, result.Allowed)
+
+	// A terceira falha do mesmo par bloqueia o par
+	result, _ = limiter.RecordLoginFailure(ctx, "10.0.0.1", "alice")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 10*time.Minute, result.BlockTime)
+
+	result, _ = limiter.CheckLogin(ctx, "10.0.0.1", "alice")
+	assert.False(t, result.Allowed)
+
+	// O mesmo usuário em outro IP e outro usuário no mesmo IP seguem livres
+	result, _ = limiter.CheckLogin(ctx, "10.0.0.2", "alice")
+	assert.True(t, result.Allowed)
+	result, _ = limiter.CheckLogin(ctx, "10.0.0.1", "bob")
+	assert.True(t, result.Allowed)
+}
+
+func TestLogin_BlocksUserAcrossIPs(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := setupLoginRateLimiter()
+
+	// Ataque distribuído: uma falha por IP, sempre contra o mesmo usuário
+	for i := 1; i <= 5; i++ {
+		failLogins(limiter, fmt.Sprintf("10.0.0.%d", i), "alice", 1)
+	}
+
+	result, _ := limiter.CheckLogin(ctx, "10.0.0.9", "alice")
+	assert.False(t, result.Allowed)
+	result, _ = limiter.CheckLogin(ctx, "10.0.0.9", "bob")
+	assert.True(t, result.Allowed)
+}
+
+func TestLogin_TokenCannotReachLoginKeys(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := setupLoginRateLimiter()
+
+	failLogins(limiter, "10.0.0.1", "alice", 1)
+
+	// Um API_KEY com o prefixo reservado é tratado como requisição sem token
+	for i := 0; i < 101; i++ {
+		limiter.AllowRequest(ctx, "10.0.0.50", "login:user:alice")
+	}
+
+	result, _ := limiter.CheckLogin(ctx, "10.0.0.2", "alice")
+	assert.True(t, result.Allowed, "O token não pode bloquear o login da alice")
+	count, _ := limiter.storage.GetRequestCount(ctx, "login:user:alice")
+	assert.Zero(t, count, "O token não pode abrir contador na chave de login")
+	failures, _ := limiter.storage.IncrementStrikes(ctx, "login:user:alice", 15*time.Minute)
+	assert.Equal(t, 2, failures, "As falhas da alice seguem intactas")
+
+	result, _ = limiter.AllowRequest(ctx, "10.0.0.50", "")
+	assert.False(t, result.Allowed, "As requisições contam para o IP")
+}
+
+func TestLogin_BlocksIPAcrossUsers(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := setupLoginRateLimiter()
+
+	// Password spraying: o mesmo IP testando um usuário diferente a cada vez
+	for _, username := range strings.Split("a b c d e f g h i j", " ") {
+		failLogins(limiter, "10.0.0.1", username, 1)
+	}
+
+	result, _ := limiter.CheckLogin(ctx, "10.0.0.1", "zoe")
+	assert.False(t, result.Allowed)
+	result, _ = limiter.CheckLogin(ctx, "10.0.0.2", "zoe")
+	assert.True(t, result.Allowed)
+}
+
+func TestLogin_FailuresDecayAfterWindow(t *testing.T) {
+	ctx := context.Background()
+	limiter, clock := setupLoginRateLimiter()
+
+	failLogins(limiter, "10.0.0.1", "alice", 2)
+
+	// Sem falhas por uma janela inteira, a contagem recomeça
+	clock.Advance(15 * time.Minute)
+	failLogins(limiter, "10.0.0.1", "alice", 2)
+
+	result, _ := limiter.CheckLogin(ctx, "10.0.0.1", "alice")
+	assert.True(t, result.Allowed)
+
+	// O bloqueio expira depois de BlockTime
+	failLogins(limiter, "10.0.0.1", "alice", 1)
+	clock.Advance(10 * time.Minute)
+	result, _ = limiter.CheckLogin(ctx, "10.0.0.1", "alice")
+	assert.True(t, result.Allowed)
+}
+
+func TestLogin_UsernameIsNormalized(t *testing.T) {
+	ctx := context.Background()
+	limiter, _ := setupLoginRateLimiter()
+
+	failLogins(limiter, "10.0.0.1", "Alice", 1)
+	failLogins(limiter, "10.0.0.1", " alice ", 1)
+	failLogins(limiter, "10.0.0.1", "ALICE", 1)
+
+	result, _ := limiter.CheckLogin(ctx, "10.0.0.1", "alice")
+	assert.False(t, result.Allowed)
+}
+
+func TestLogin_FailClosed(t *testing.T) {
+	limiter := setupFailingRateLimiter(FailClosed)
+	limiter.config.Login = LoginPolicy{MaxFailuresPerIP: 1, Window: time.Minute, BlockTime: time.Minute}
+
+	result, err := limiter.CheckLogin(context.Background(), "10.0.0.1", "alice")
+	assert.Error(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, FailClosed, result.FailureMode)
+}
+
+func setupLoginMiddleware() (*gin.Engine, *storagetest.Clock) {
+	gin.SetMode(gin.TestMode)
+
+	rateLimiter, clock := setupLoginRateLimiter()
+	router := gin.New()
+	router.Use(RateLimiterMiddleware(rateLimiter))
+
+	router.POST("/login", func(c *gin.Context) {
+		var credentials struct {
+			Username string `json:"username" form:"username"`
+			Password string `json:"password" form:"password"`
+		}
+		if err := c.ShouldBind(&credentials); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		if credentials.Password != "secret" {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	return router, clock
+}
+
+func postLogin(router *gin.Engine, username, password string) *httptest.ResponseRecorder {
+	form := url.Values{"username": {username}, "password": {password}}
+	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "10.0.0.1:12345"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_LoginCountsOnlyFailures(t *testing.T) {
+	router, _ := setupLoginMiddleware()
+
+	// Logins bem-sucedidos não contam
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, postLogin(router, "alice", "secret").Code)
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, postLogin(router, "alice", "errada").Code)
+	}
+
+	// O par está bloqueado, até com a senha certa, e o handler não chega a rodar
+	w := postLogin(router, "alice", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "600", w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, postLogin(router, "bob", "secret").Code)
+}
+
+func TestMiddleware_LoginFlagOverridesStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rateLimiter, _ := setupLoginRateLimiter()
+
+	router := gin.New()
+	router.Use(RateLimiterMiddleware(rateLimiter))
+	router.POST("/login", func(c *gin.Context) {
+		// 401 pedindo o segundo fator não é uma falha de senha
+		MarkLoginFailed(c, c.PostForm("password") != "secret")
+		c.Status(http.StatusUnauthorized)
+	})
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusUnauthorized, postLogin(router, "alice", "secret").Code)
+	}
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, postLogin(router, "alice", "errada").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, postLogin(router, "alice", "secret").Code)
+}
+
+func TestMiddleware_LoginReadsJSONUsername(t *testing.T) {
+	router, _ := setupLoginMiddleware()
+
+	post := func(body string) int {
+		req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.0.1:12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// O handler continua recebendo o corpo inteiro depois que o middleware leu o usuário
+	assert.Equal(t, http.StatusOK, post(`{"username":"alice","password":"secret"}`))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, post(`{"username":"alice","password":"errada"}`))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, post(`{"username":"alice","password":"secret"}`))
+	assert.Equal(t, http.StatusOK, post(`{"username":"bob","password":"secret"}`))
+}
@@ -1,29 +1,137 @@
 package limiter
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
 	"net/http"
 	"rate-limiter/config"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 )
 
+// LoginFailedKey é a flag que o handler de login grava no contexto do Gin com o
+// resultado da tentativa; quando presente, prevalece sobre o status da resposta.
+const LoginFailedKey = "rate_limiter.login_failed"
+
+// maxLoginBodyBytes limita quanto do corpo JSON é lido para encontrar o usuário.
+const maxLoginBodyBytes = 64 << 10
+
+// MarkLoginFailed informa ao middleware se a tentativa de login falhou.
+func MarkLoginFailed(c *gin.Context, failed bool) {
+	c.Set(LoginFailedKey, failed)
+}
+
 func RateLimiterMiddleware(rateLimiter *RateLimiterService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := c.ClientIP()
 		token := c.GetHeader("API_KEY")
 
-		result := rateLimiter.AllowRequest(ip, token)
-		if !result.Allowed {
-			config.Logger.Warn("Requisição bloqueada pelo Rate Limiter", zap.String("ip", ip), zap.String("token", token))
+		// Continua o trace iniciado pelo cliente (traceparent), se houver
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		c.Request = c.Request.WithContext(ctx)
 
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"message":  "You have reached the maximum number of requests or actions allowed within a certain time frame",
-				"retry_in": result.BlockTime.Seconds(),
-			})
+		start := time.Now()
+		// Erros do storage já foram registrados pelo serviço e refletidos no resultado
+		result, err := rateLimiter.AllowRequest(ctx, ip, token)
+		rateLimiter.metrics.ObserveCheck(time.Since(start))
+
+		if abortIfRejected(ctx, c, result, err, "Requisição bloqueada pelo Rate Limiter", zap.String("ip", ip), zap.String("token", token)) {
 			return
 		}
 
+		login := rateLimiter.config.Login
+		protectsLogin := login.Protects(c.Request.URL.Path)
+		var username string
+		if protectsLogin {
+			username = loginUsername(c, login.UsernameField)
+			result, err = rateLimiter.CheckLogin(ctx, ip, username)
+			if abortIfRejected(ctx, c, result, err, "Login bloqueado pelo Rate Limiter", zap.String("ip", ip), zap.String("username", username)) {
+				return
+			}
+		}
+
 		c.Next()
+
+		// A contagem depois da resposta ignora o cancelamento da requisição, para que
+		// o cliente não escape dela desconectando antes do fim
+		ctx = context.WithoutCancel(ctx)
+		rateLimiter.RecordResponse(ctx, ip, token, c.Writer.Status())
+		if protectsLogin && loginFailed(c, login) {
+			rateLimiter.RecordLoginFailure(ctx, ip, username)
+		}
+	}
+}
+
+// abortIfRejected encerra a requisição quando o resultado não permite que ela siga.
+func abortIfRejected(ctx context.Context, c *gin.Context, result RateLimitResult, err error, blockedMessage string, fields ...zap.Field) bool {
+	if err != nil && ctx.Err() != nil {
+		// Cliente desconectado: não há para quem responder
+		c.Abort()
+		return true
+	}
+
+	if result.FailureMode == FailClosed {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"message": "Rate limiter temporarily unavailable",
+		})
+		return true
+	}
+
+	if !result.Allowed {
+		config.Logger.Warn(blockedMessage, fields...)
+
+		// Retry-After só aceita segundos inteiros; arredonda para cima para o cliente não voltar cedo demais
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.BlockTime.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"message":  "You have reached the maximum number of requests or actions allowed within a certain time frame",
+			"retry_in": result.BlockTime.Seconds(),
+		})
+		return true
+	}
+	return false
+}
+
+// loginUsername lê o usuário do corpo JSON ou do formulário sem tirar o corpo do handler.
+func loginUsername(c *gin.Context, field string) string {
+	if field == "" {
+		field = "username"
+	}
+	if c.ContentType() != binding.MIMEJSON {
+		return c.PostForm(field)
+	}
+
+	original := c.Request.Body
+	body, err := io.ReadAll(io.LimitReader(original, maxLoginBodyBytes))
+	// O que foi lido volta para a frente do restante do corpo
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), original), original}
+	if err != nil {
+		return ""
+	}
+
+	var payload map[string]any
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	username, _ := payload[field].(string)
+	return username
+}
+
+func loginFailed(c *gin.Context, login LoginPolicy) bool {
+	if value, exists := c.Get(LoginFailedKey); exists {
+		failed, _ := value.(bool)
+		return failed
 	}
+	return login.IsFailureStatus(c.Writer.Status())
 }
@@ -1,24 +1,33 @@
 package limiter
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"rate-limiter/config"
 	"rate-limiter/internal/storage"
+	"rate-limiter/internal/storage/storagetest"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
-func setupTestMiddleware() (*gin.Engine, *RateLimiterService) {
+func setupTestMiddleware() (*gin.Engine, *storagetest.Clock) {
 	gin.SetMode(gin.TestMode)
 
 	config.InitLogger()
 
-	// Criar armazenamento em memória para os testes
-	memStorage := storage.NewMemoryStorage()
+	// Criar armazenamento em memória para os testes, com relógio controlado pelo teste
+	clock := storagetest.NewClock()
+	memStorage := storage.NewMemoryStorageWithOptions(storage.MemoryOptions{Clock: clock})
 
 	// Criar serviço de Rate Limiter
 	rateLimiter := NewRateLimiterService(memStorage, RateLimiterConfig{
@@ -26,7 +35,7 @@ func setupTestMiddleware() (*gin.Engine, *RateLimiterService) {
 		RateLimitPerToken:     5,
 		DefaultBlockTimeIP:    120,
 		DefaultBlockTimeToken: 300,
-	}, config.Logger)
+	}, config.Logger).WithClock(clock)
 
 	// Criar servidor Gin de teste
 	router := gin.New()
@@ -37,11 +46,11 @@ func setupTestMiddleware() (*gin.Engine, *RateLimiterService) {
 		c.JSON(http.StatusOK, gin.H{"message": "Requisição permitida"})
 	})
 
-	return router, rateLimiter
+	return router, clock
 }
 
 func TestMiddleware_IPBlocking(t *testing.T) {
-	router, _ := setupTestMiddleware()
+	router, clock := setupTestMiddleware()
 
 	// Criar requisição simulada
 	req, _ := http.NewRequest("GET", "/test", nil)
@@ -58,10 +67,22 @@ func TestMiddleware_IPBlocking(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Ainda bloqueado um segundo antes do fim dos 120 segundos
+	clock.Advance(119 * time.Second)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// Bloqueio expirado: a requisição volta a passar
+	clock.Advance(time.Second)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
 }
 
 func TestMiddleware_TokenBlocking(t *testing.T) {
-	router, _ := setupTestMiddleware()
+	router, clock := setupTestMiddleware()
 
 	// Criar requisição simulada com Token
 	req, _ := http.NewRequest("GET", "/test", nil)
@@ -78,6 +99,28 @@ func TestMiddleware_TokenBlocking(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	clock.Advance(300 * time.Second)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMiddleware_WindowRollover(t *testing.T) {
+	router, clock := setupTestMiddleware()
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.5:12345"
+
+	// O limite de 3 vale por janela de um minuto: 3 requisições em cada janela passam
+	for window := 0; window < 3; window++ {
+		for i := 0; i < 3; i++ {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, "Janela %d, requisição %d", window+1, i+1)
+		}
+		clock.Advance(time.Minute)
+	}
 }
 
 func TestMiddleware_TokenTakesPriorityOverIP(t *testing.T) {
@@ -136,4 +179,131 @@ func TestMiddleware_ResponseBody(t *testing.T) {
 	router.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusTooManyRequests, w.Code)
 	assert.Contains(t, w.Body.String(), "You have reached the maximum number of requests or actions allowed within a certain time frame")
+	assert.Equal(t, 120.0, retryIn(t, w))
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+}
+
+func TestMiddleware_RetryInCountsDown(t *testing.T) {
+	router, clock := setupTestMiddleware()
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	for i := 0; i < 4; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Requisições seguintes recebem o tempo que falta, não a duração original
+	clock.Advance(45 * time.Second)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, 75.0, retryIn(t, w))
+	assert.Equal(t, "75", w.Header().Get("Retry-After"))
+
+	// Frações de segundo arredondam para cima no cabeçalho
+	clock.Advance(74*time.Second + 500*time.Millisecond)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 0.5, retryIn(t, w))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
+
+func retryIn(t *testing.T, w *httptest.ResponseRecorder) float64 {
+	var body struct {
+		RetryIn float64 `json:"retry_in"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.RetryIn
+}
+
+func TestMiddleware_PropagatesTraceContext(t *testing.T) {
+	// Provider e propagador são globais; os anteriores voltam ao fim do teste
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	router, _ := setupTestMiddleware()
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Todos os spans do limiter devem pertencer ao trace recebido no cabeçalho
+	spans := recorder.Ended()
+	assert.NotEmpty(t, spans)
+	for _, span := range spans {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	}
+}
+
+func TestMiddleware_FailClosedReturnsServiceUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RateLimiterMiddleware(setupFailingRateLimiter(FailClosed)))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "Requisição permitida"})
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestMiddleware_CountsAfterResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.InitLogger()
+
+	countedStatuses, _ := ParseStatusFilter("!304,!5xx")
+	rateLimiter := NewRateLimiterService(storage.NewMemoryStorage(), RateLimiterConfig{
+		RateLimitPerIP:     3,
+		RateLimitPerToken:  5,
+		DefaultBlockTimeIP: 120,
+		CountedStatusesIP:  countedStatuses,
+	}, config.Logger)
+
+	handlerCalls := 0
+	router := gin.New()
+	router.Use(RateLimiterMiddleware(rateLimiter))
+	router.GET("/test/:status", func(c *gin.Context) {
+		handlerCalls++
+		status, _ := strconv.Atoi(c.Param("status"))
+		c.Status(status)
+	})
+
+	get := func(status string) int {
+		req, _ := http.NewRequest("GET", "/test/"+status, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 304 e erros do próprio servidor não consomem a cota
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusNotModified, get("304"))
+		assert.Equal(t, http.StatusInternalServerError, get("500"))
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, get("200"))
+	}
+
+	// A terceira resposta contada bloqueou o IP antes de chegar ao handler
+	calls := handlerCalls
+	assert.Equal(t, http.StatusTooManyRequests, get("200"))
+	assert.Equal(t, calls, handlerCalls)
 }
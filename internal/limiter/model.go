@@ -1,6 +1,24 @@
 package limiter
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FailureMode define o que o limiter faz quando o storage retorna erro.
+type FailureMode string
+
+const (
+	// FailOpen permite a requisição (comportamento histórico).
+	FailOpen FailureMode = "open"
+	// FailClosed rejeita a requisição enquanto o storage estiver indisponível.
+	FailClosed FailureMode = "closed"
+	// FailLocal aplica os mesmos limites usando um armazenamento em memória local.
+	FailLocal FailureMode = "local"
+)
 
 type RateLimiterConfig struct {
 	RateLimitPerIP        int
@@ -9,9 +27,153 @@ type RateLimiterConfig struct {
 	BlockTimePerToken     map[string]int
 	DefaultBlockTimeIP    int
 	DefaultBlockTimeToken int
+	FailureModeIP         FailureMode
+	FailureModeToken      FailureMode
+	// StorageTimeout limita cada chamada ao storage (zero desativa).
+	StorageTimeout time.Duration
+	PenaltyIP      PenaltyPolicy
+	PenaltyToken   PenaltyPolicy
+	Login          LoginPolicy
+	// CountedStatuses* adiam a contagem para depois da resposta e contam só os status
+	// escolhidos; vazio conta todas as requisições antes do handler.
+	CountedStatusesIP    StatusFilter
+	CountedStatusesToken StatusFilter
+}
+
+// PenaltyPolicy escala o bloqueio de quem reincide: a n-ésima violação bloqueia por
+// Steps[n-1], repetindo a última etapa depois disso. As violações ficam no storage e
+// são esquecidas quando passa Decay sem nenhuma nova. Sem Steps vale o tempo fixo.
+type PenaltyPolicy struct {
+	Steps []time.Duration
+	Decay time.Duration
+}
+
+func (p PenaltyPolicy) Enabled() bool {
+	return len(p.Steps) > 0 && p.Decay > 0
+}
+
+// StepFor devolve a duração do bloqueio para a violação de número strikes (a partir de 1).
+func (p PenaltyPolicy) StepFor(strikes int) time.Duration {
+	return p.Steps[min(max(strikes, 1), len(p.Steps))-1]
+}
+
+// LoginPolicy protege rotas de autenticação contra força bruta. Só as tentativas que
+// falharam contam, por IP, por usuário e pelo par usuário+IP; um limite zero desliga a
+// dimensão. As falhas são esquecidas quando passa Window sem nenhuma nova.
+type LoginPolicy struct {
+	Paths                []string
+	UsernameField        string
+	MaxFailuresPerIP     int
+	MaxFailuresPerUser   int
+	MaxFailuresPerUserIP int
+	Window               time.Duration
+	BlockTime            time.Duration
+	// FailureStatuses lista os status de resposta que contam como falha (padrão: 401).
+	FailureStatuses []int
+}
+
+func (p LoginPolicy) Protects(path string) bool {
+	return slices.Contains(p.Paths, path)
+}
+
+func (p LoginPolicy) IsFailureStatus(status int) bool {
+	if len(p.FailureStatuses) == 0 {
+		return status == 401
+	}
+	return slices.Contains(p.FailureStatuses, status)
+}
+
+// StatusFilter escolhe quais respostas contam para o limite, a partir de uma lista como
+// "4xx" ou "!304,!5xx": códigos exatos ou classes, com "!" para excluir. Só com
+// exclusões, todos os outros status contam.
+type StatusFilter struct {
+	include []statusPattern
+	exclude []statusPattern
+}
+
+// statusPattern casa um código exato ou, com class preenchido, uma classe inteira (4xx).
+type statusPattern struct {
+	code  int
+	class int
+}
+
+func (p statusPattern) matches(status int) bool {
+	if p.class != 0 {
+		return status/100 == p.class
+	}
+	return status == p.code
+}
+
+func ParseStatusFilter(value string) (StatusFilter, error) {
+	var filter StatusFilter
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pattern, err := parseStatusPattern(strings.TrimPrefix(item, "!"))
+		if err != nil {
+			return StatusFilter{}, err
+		}
+		if strings.HasPrefix(item, "!") {
+			filter.exclude = append(filter.exclude, pattern)
+		} else {
+			filter.include = append(filter.include, pattern)
+		}
+	}
+	return filter, nil
 }
 
+func parseStatusPattern(item string) (statusPattern, error) {
+	if len(item) == 3 && strings.EqualFold(item[1:], "xx") && item[0] >= '1' && item[0] <= '5' {
+		return statusPattern{class: int(item[0] - '0')}, nil
+	}
+	code, err := strconv.Atoi(item)
+	if err != nil || code < 100 || code > 599 {
+		return statusPattern{}, fmt.Errorf("status inválido: %q", item)
+	}
+	return statusPattern{code: code}, nil
+}
+
+// Enabled indica que a contagem acontece depois da resposta.
+func (f StatusFilter) Enabled() bool {
+	return len(f.include) > 0 || len(f.exclude) > 0
+}
+
+func (f StatusFilter) Matches(status int) bool {
+	for _, pattern := range f.exclude {
+		if pattern.matches(status) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if pattern.matches(status) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitResult descreve a decisão do limiter. FailureMode só é preenchido
+// quando o storage falhou e a decisão seguiu o modo de falha da política.
 type RateLimitResult struct {
-	Allowed   bool
-	BlockTime time.Duration
+	Allowed bool
+	// BlockTime é quanto falta para o bloqueio expirar: a duração inteira na requisição
+	// que bloqueia a chave e o tempo restante nas seguintes.
+	BlockTime   time.Duration
+	FailureMode FailureMode
+}
+
+func ParseFailureMode(value string) (FailureMode, bool) {
+	switch mode := FailureMode(value); mode {
+	case FailOpen, FailClosed, FailLocal:
+		return mode, true
+	case "":
+		return FailOpen, true
+	default:
+		return "", false
+	}
 }
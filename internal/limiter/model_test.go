@@ -34,3 +34,51 @@ func TestRateLimitResultInitialization(t *testing.T) {
 	assert.False(t, result.Allowed)
 	assert.Equal(t, 30*time.Second, result.BlockTime)
 }
+
+func TestPenaltyPolicy_StepFor(t *testing.T) {
+	penalty := PenaltyPolicy{
+		Steps: []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 24 * time.Hour},
+		Decay: 24 * time.Hour,
+	}
+
+	assert.True(t, penalty.Enabled())
+	assert.Equal(t, time.Minute, penalty.StepFor(1))
+	assert.Equal(t, 30*time.Minute, penalty.StepFor(3))
+	// Depois da última etapa, ela se repete
+	assert.Equal(t, 24*time.Hour, penalty.StepFor(4))
+	assert.Equal(t, 24*time.Hour, penalty.StepFor(10))
+
+	assert.False(t, PenaltyPolicy{Decay: time.Hour}.Enabled())
+	assert.False(t, PenaltyPolicy{Steps: []time.Duration{time.Minute}}.Enabled())
+}
+
+func TestStatusFilter(t *testing.T) {
+	onlyClientErrors, err := ParseStatusFilter("4xx")
+	assert.NoError(t, err)
+	assert.True(t, onlyClientErrors.Enabled())
+	assert.True(t, onlyClientErrors.Matches(404))
+	assert.False(t, onlyClientErrors.Matches(200))
+	assert.False(t, onlyClientErrors.Matches(500))
+
+	// Só com exclusões, o resto conta
+	exceptOurs, err := ParseStatusFilter("!304, !5xx")
+	assert.NoError(t, err)
+	assert.True(t, exceptOurs.Matches(200))
+	assert.True(t, exceptOurs.Matches(429))
+	assert.False(t, exceptOurs.Matches(304))
+	assert.False(t, exceptOurs.Matches(503))
+
+	// A exclusão prevalece sobre a classe incluída
+	mixed, _ := ParseStatusFilter("4xx,!404")
+	assert.True(t, mixed.Matches(401))
+	assert.False(t, mixed.Matches(404))
+
+	empty, err := ParseStatusFilter("")
+	assert.NoError(t, err)
+	assert.False(t, empty.Enabled())
+
+	for _, invalid := range []string{"4x", "600", "abc", "!6xx"} {
+		_, err := ParseStatusFilter(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -1,64 +1,344 @@
 package limiter
 
 import (
+	"context"
+	"rate-limiter/internal/metrics"
 	"rate-limiter/internal/storage"
+	"rate-limiter/internal/tracing"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+const (
+	dimensionIP    = "ip"
+	dimensionToken = "token"
+
+	policyDefault = "default"
+	policyCustom  = "custom"
+)
+
 type RateLimiterService struct {
-	storage storage.RateLimiterStorage
-	config  RateLimiterConfig
-	logger  *zap.Logger
+	storage  storage.RateLimiterStorage
+	fallback storage.RateLimiterStorage
+	config   RateLimiterConfig
+	logger   *zap.Logger
+	metrics  *metrics.Metrics
+}
+
+// dimensionPolicy reúne as regras aplicadas a uma dimensão (IP ou token).
+type dimensionPolicy struct {
+	name        string
+	label       string
+	limit       int
+	overrides   map[string]int
+	blockTime   func(string) time.Duration
+	penalty     PenaltyPolicy
+	failureMode FailureMode
+	// counted adia a contagem para RecordResponse quando habilitado.
+	counted StatusFilter
 }
 
-func NewRateLimiterService(storage storage.RateLimiterStorage, cfg RateLimiterConfig, logger *zap.Logger) *RateLimiterService {
+func NewRateLimiterService(rateLimiterStorage storage.RateLimiterStorage, cfg RateLimiterConfig, logger *zap.Logger) *RateLimiterService {
 	return &RateLimiterService{
-		storage: storage,
-		config:  cfg,
-		logger:  logger,
+		storage:  rateLimiterStorage,
+		fallback: storage.NewMemoryStorage(),
+		config:   cfg,
+		logger:   logger,
 	}
 }
 
-func (rl *RateLimiterService) AllowRequest(ip, token string) RateLimitResult {
-	if token != "" {
-		if blocked, _ := rl.storage.IsBlocked(token); blocked {
-			blockTime, _ := rl.storage.GetBlockDuration(token)
-			rl.logger.Warn("Token bloqueado", zap.String("token", token), zap.Duration("block_time", blockTime))
-			return RateLimitResult{Allowed: false, BlockTime: blockTime}
+// WithClock recria o fallback em memória com o relógio informado; os testes usam um
+// relógio falso para avançar janelas e bloqueios sem dormir. O storage principal
+// recebe o mesmo relógio por conta própria.
+func (rl *RateLimiterService) WithClock(clock storage.Clock) *RateLimiterService {
+	rl.fallback = storage.NewMemoryStorageWithOptions(storage.MemoryOptions{Clock: clock})
+	return rl
+}
+
+func (rl *RateLimiterService) WithMetrics(m *metrics.Metrics) *RateLimiterService {
+	rl.metrics = m
+	return rl
+}
+
+// AllowRequest decide se a requisição pode seguir. O erro só é diferente de nil
+// quando o storage falhou; nesse caso o resultado já reflete o modo de falha
+// configurado para a dimensão.
+func (rl *RateLimiterService) AllowRequest(ctx context.Context, ip, token string) (RateLimitResult, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "RateLimiterService.AllowRequest")
+	defer span.End()
+
+	key, policy := rl.requestPolicy(ip, token)
+
+	result, err := rl.allow(ctx, span, rl.storage, policy, key)
+	if err != nil && ctx.Err() != nil {
+		// O cliente desistiu da requisição; não há decisão a tomar nem falha do storage a registrar
+		return RateLimitResult{}, ctx.Err()
+	}
+	if err != nil {
+		result = rl.handleStorageFailure(ctx, span, policy, key, err)
+	}
+
+	span.SetAttributes(attribute.Bool("rate_limiter.allowed", result.Allowed))
+	if !result.Allowed {
+		span.SetAttributes(attribute.Float64("rate_limiter.block_time_seconds", result.BlockTime.Seconds()))
+	}
+	return result, err
+}
+
+func (rl *RateLimiterService) allow(ctx context.Context, span trace.Span, store storage.RateLimiterStorage, policy dimensionPolicy, key string) (RateLimitResult, error) {
+	policyName := rl.policyFor(policy.overrides, key)
+	span.SetAttributes(
+		attribute.String("rate_limiter.dimension", policy.name),
+		attribute.String("rate_limiter.policy", policyName),
+	)
+
+	var blocked bool
+	err := rl.traceStorage(ctx, "IsBlocked", func(ctx context.Context) (err error) {
+		blocked, err = store.IsBlocked(ctx, key)
+		return err
+	})
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if blocked {
+		var blockTime time.Duration
+		err := rl.traceStorage(ctx, "GetBlockRemaining", func(ctx context.Context) (err error) {
+			blockTime, err = store.GetBlockRemaining(ctx, key)
+			return err
+		})
+		if err != nil {
+			// A chave está bloqueada; só não sabemos por quanto tempo
+			rl.logger.Error("Erro ao obter tempo restante do bloqueio", zap.String(policy.name, key), zap.Error(err))
+			blockTime = policy.blockTime(key)
 		}
+		rl.logger.Warn(policy.label+" bloqueado", zap.String(policy.name, key), zap.Duration("block_time", blockTime))
+		rl.metrics.ObserveDecision(policy.name, policyName, false)
+		span.SetAttributes(attribute.String("rate_limiter.decision", "already_blocked"))
+		return RateLimitResult{Allowed: false, BlockTime: blockTime}, nil
+	}
 
-		limit := rl.config.RateLimitPerToken
-		requests, _ := rl.storage.IncrementRequest(token)
+	if policy.counted.Enabled() {
+		// A contagem fica para depois da resposta, em RecordResponse
+		rl.metrics.ObserveDecision(policy.name, policyName, true)
+		span.SetAttributes(attribute.String("rate_limiter.decision", "allowed"))
+		return RateLimitResult{Allowed: true}, nil
+	}
+
+	var requests int
+	err = rl.traceStorage(ctx, "IncrementRequest", func(ctx context.Context) (err error) {
+		requests, err = store.IncrementRequest(ctx, key)
+		return err
+	})
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	span.SetAttributes(attribute.Int("rate_limiter.remaining", max(policy.limit-requests, 0)))
 
-		if requests > limit {
-			blockTime := rl.getBlockDurationForToken(token)
-			rl.storage.BlockKey(token, blockTime)
-			rl.logger.Warn("Token atingiu o limite", zap.String("token", token), zap.Int("requests", requests))
-			return RateLimitResult{Allowed: false, BlockTime: blockTime}
+	if requests > policy.limit {
+		// O limite foi excedido de qualquer forma, então a requisição é rejeitada
+		blockTime := rl.block(ctx, span, store, policy, policyName, key, requests)
+		rl.metrics.ObserveDecision(policy.name, policyName, false)
+		span.SetAttributes(attribute.String("rate_limiter.decision", "blocked"))
+		return RateLimitResult{Allowed: false, BlockTime: blockTime}, nil
+	}
+
+	rl.metrics.ObserveDecision(policy.name, policyName, true)
+	span.SetAttributes(attribute.String("rate_limiter.decision", "allowed"))
+	return RateLimitResult{Allowed: true}, nil
+}
+
+// RecordResponse conta a requisição depois da resposta nas políticas com CountedStatuses,
+// se o status estiver entre os contados. Como a resposta já foi enviada, a chave é
+// bloqueada ao completar o limite e a rejeição vale a partir da próxima requisição.
+// Nas demais políticas a contagem já aconteceu em AllowRequest.
+func (rl *RateLimiterService) RecordResponse(ctx context.Context, ip, token string, status int) (RateLimitResult, error) {
+	key, policy := rl.requestPolicy(ip, token)
+	if !policy.counted.Enabled() || !policy.counted.Matches(status) {
+		return RateLimitResult{Allowed: true}, nil
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "RateLimiterService.RecordResponse")
+	defer span.End()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+	result, err := rl.record(ctx, span, rl.storage, policy, key)
+	if err != nil && ctx.Err() != nil {
+		return RateLimitResult{}, ctx.Err()
+	}
+	if err != nil {
+		mode := policy.failureMode
+		rl.logger.Error("Falha no storage ao contar a resposta",
+			zap.String(policy.name, key),
+			zap.String("failure_mode", string(mode)),
+			zap.Error(err),
+		)
+		rl.metrics.ObserveStorageFailure(policy.name, string(mode))
+		span.SetAttributes(attribute.String("rate_limiter.failure_mode", string(mode)))
+
+		// Só o modo local tem onde contar; nos outros a resposta já foi decidida
+		result = RateLimitResult{Allowed: true, FailureMode: mode}
+		if mode == FailLocal {
+			result, _ = rl.record(ctx, span, rl.fallback, policy, key)
+			result.FailureMode = FailLocal
 		}
+	}
+	return result, err
+}
+
+func (rl *RateLimiterService) record(ctx context.Context, span trace.Span, store storage.RateLimiterStorage, policy dimensionPolicy, key string) (RateLimitResult, error) {
+	policyName := rl.policyFor(policy.overrides, key)
+	span.SetAttributes(
+		attribute.String("rate_limiter.dimension", policy.name),
+		attribute.String("rate_limiter.policy", policyName),
+	)
+
+	var requests int
+	err := rl.traceStorage(ctx, "IncrementRequest", func(ctx context.Context) (err error) {
+		requests, err = store.IncrementRequest(ctx, key)
+		return err
+	})
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	span.SetAttributes(attribute.Int("rate_limiter.remaining", max(policy.limit-requests, 0)))
+
+	// Diferente da contagem antecipada, a requisição que completa o limite já foi
+	// atendida, então o bloqueio começa nela e não na seguinte
+	if requests >= policy.limit {
+		blockTime := rl.block(ctx, span, store, policy, policyName, key, requests)
+		return RateLimitResult{Allowed: false, BlockTime: blockTime}, nil
+	}
+	return RateLimitResult{Allowed: true}, nil
+}
+
+// block bloqueia a chave que excedeu o limite e devolve a duração aplicada.
+func (rl *RateLimiterService) block(ctx context.Context, span trace.Span, store storage.RateLimiterStorage, policy dimensionPolicy, policyName, key string, requests int) time.Duration {
+	blockTime := rl.penaltyFor(ctx, span, store, policy, policyName, key)
+	err := rl.traceStorage(ctx, "BlockKey", func(ctx context.Context) error {
+		return store.BlockKey(ctx, key, blockTime)
+	})
+	if err != nil {
+		rl.logger.Error("Erro ao bloquear chave", zap.String(policy.name, key), zap.Error(err))
+	}
+	rl.logger.Warn(policy.label+" atingiu o limite", zap.String(policy.name, key), zap.Int("requests", requests), zap.Duration("block_time", blockTime))
+	return blockTime
+}
+
+// penaltyFor escala o bloqueio das chaves da política padrão conforme as reincidências.
+// Chaves com tempo de bloqueio específico mantêm o tempo configurado para elas.
+func (rl *RateLimiterService) penaltyFor(ctx context.Context, span trace.Span, store storage.RateLimiterStorage, policy dimensionPolicy, policyName, key string) time.Duration {
+	if policyName == policyCustom || !policy.penalty.Enabled() {
+		return policy.blockTime(key)
+	}
+
+	var strikes int
+	err := rl.traceStorage(ctx, "IncrementStrikes", func(ctx context.Context) (err error) {
+		strikes, err = store.IncrementStrikes(ctx, key, policy.penalty.Decay)
+		return err
+	})
+	if err != nil {
+		// Sem o histórico, aplica o tempo padrão em vez de deixar passar
+		rl.logger.Error("Erro ao registrar reincidência", zap.String(policy.name, key), zap.Error(err))
+		return policy.blockTime(key)
+	}
+	span.SetAttributes(attribute.Int("rate_limiter.strikes", strikes))
+	return policy.penalty.StepFor(strikes)
+}
 
-		return RateLimitResult{Allowed: true}
+func (rl *RateLimiterService) handleStorageFailure(ctx context.Context, span trace.Span, policy dimensionPolicy, key string, err error) RateLimitResult {
+	mode := policy.failureMode
+	rl.logger.Error("Falha no storage do Rate Limiter",
+		zap.String(policy.name, key),
+		zap.String("failure_mode", string(mode)),
+		zap.Error(err),
+	)
+	rl.metrics.ObserveStorageFailure(policy.name, string(mode))
+	span.SetAttributes(attribute.String("rate_limiter.failure_mode", string(mode)))
+
+	switch mode {
+	case FailClosed:
+		rl.metrics.ObserveDecision(policy.name, rl.policyFor(policy.overrides, key), false)
+		span.SetAttributes(attribute.String("rate_limiter.decision", "failed_closed"))
+		return RateLimitResult{Allowed: false, BlockTime: policy.blockTime(key), FailureMode: FailClosed}
+	case FailLocal:
+		result, _ := rl.allow(ctx, span, rl.fallback, policy, key)
+		result.FailureMode = FailLocal
+		return result
+	default:
+		rl.metrics.ObserveDecision(policy.name, rl.policyFor(policy.overrides, key), true)
+		span.SetAttributes(attribute.String("rate_limiter.decision", "failed_open"))
+		return RateLimitResult{Allowed: true, FailureMode: FailOpen}
 	}
+}
 
-	if blocked, _ := rl.storage.IsBlocked(ip); blocked {
-		blockTime, _ := rl.storage.GetBlockDuration(ip)
-		rl.logger.Warn("IP bloqueado", zap.String("ip", ip), zap.Duration("block_time", blockTime))
-		return RateLimitResult{Allowed: false, BlockTime: blockTime}
+// traceStorage envolve uma chamada ao storage em um span filho do AllowRequest
+// e limita a chamada ao orçamento StorageTimeout, para que um Redis travado não
+// prenda a requisição.
+func (rl *RateLimiterService) traceStorage(ctx context.Context, operation string, call func(context.Context) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, "RateLimiterStorage."+operation, trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	if rl.config.StorageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rl.config.StorageTimeout)
+		defer cancel()
 	}
 
-	limit := rl.config.RateLimitPerIP
-	requests, _ := rl.storage.IncrementRequest(ip)
+	err := call(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
 
-	if requests > limit {
-		blockTime := rl.getBlockDurationForIP(ip)
-		rl.storage.BlockKey(ip, blockTime)
-		rl.logger.Warn("IP atingiu o limite", zap.String("ip", ip), zap.Int("requests", requests))
-		return RateLimitResult{Allowed: false, BlockTime: blockTime}
+// requestPolicy escolhe a chave e a política da requisição: o token tem prioridade sobre o IP.
+// Tokens com o prefixo das chaves de login dividiriam contadores e bloqueios com elas
+// (API_KEY: login:user:alice bloquearia a alice), então valem como requisição sem token.
+func (rl *RateLimiterService) requestPolicy(ip, token string) (string, dimensionPolicy) {
+	if token != "" && !strings.HasPrefix(token, loginKeyPrefix) {
+		return token, rl.tokenPolicy()
 	}
+	return ip, rl.ipPolicy()
+}
 
-	return RateLimitResult{Allowed: true}
+func (rl *RateLimiterService) ipPolicy() dimensionPolicy {
+	return dimensionPolicy{
+		name:        dimensionIP,
+		label:       "IP",
+		limit:       rl.config.RateLimitPerIP,
+		overrides:   rl.config.BlockTimePerIP,
+		blockTime:   rl.getBlockDurationForIP,
+		penalty:     rl.config.PenaltyIP,
+		failureMode: rl.config.FailureModeIP,
+		counted:     rl.config.CountedStatusesIP,
+	}
+}
+
+func (rl *RateLimiterService) tokenPolicy() dimensionPolicy {
+	return dimensionPolicy{
+		name:        dimensionToken,
+		label:       "Token",
+		limit:       rl.config.RateLimitPerToken,
+		overrides:   rl.config.BlockTimePerToken,
+		blockTime:   rl.getBlockDurationForToken,
+		penalty:     rl.config.PenaltyToken,
+		failureMode: rl.config.FailureModeToken,
+		counted:     rl.config.CountedStatusesToken,
+	}
+}
+
+// policyFor indica se a chave usa a política padrão ou um tempo de bloqueio específico.
+func (rl *RateLimiterService) policyFor(overrides map[string]int, key string) string {
+	if _, exists := overrides[key]; exists {
+		return policyCustom
+	}
+	return policyDefault
 }
 
 func (rl *RateLimiterService) getBlockDurationForIP(ip string) time.Duration {
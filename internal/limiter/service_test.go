@@ -1,19 +1,32 @@
 package limiter
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"rate-limiter/config"
+	"rate-limiter/internal/metrics"
 	"rate-limiter/internal/storage"
+	"rate-limiter/internal/storage/storagetest"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
-func setupTestRateLimiter() *RateLimiterService {
+// setupTestRateLimiter usa um relógio falso, para que janelas e bloqueios expirem
+// com clock.Advance em vez de sleeps.
+func setupTestRateLimiter() (*RateLimiterService, *storagetest.Clock) {
 	config.InitLogger()
 
-	memStorage := storage.NewMemoryStorage()
+	clock := storagetest.NewClock()
+	memStorage := storage.NewMemoryStorageWithOptions(storage.MemoryOptions{Clock: clock})
 
 	testConfig := RateLimiterConfig{
 		RateLimitPerIP:        5,
@@ -24,67 +37,404 @@ func setupTestRateLimiter() *RateLimiterService {
 		DefaultBlockTimeToken: 360,
 	}
 
-	return NewRateLimiterService(memStorage, testConfig, config.Logger)
+	return NewRateLimiterService(memStorage, testConfig, config.Logger).WithClock(clock), clock
 }
 
 func TestRateLimiter_IPBlocking(t *testing.T) {
-	limiter := setupTestRateLimiter()
+	ctx := context.Background()
+	limiter, clock := setupTestRateLimiter()
 	ip := "192.168.1.1"
 
 	// Permitir requisições até o limite
 	for i := 0; i < limiter.config.RateLimitPerIP; i++ {
-		result := limiter.AllowRequest(ip, "")
+		result, _ := limiter.AllowRequest(context.Background(), ip, "")
 		assert.True(t, result.Allowed)
 	}
 
 	// Atingiu o limite, deve bloquear
-	result := limiter.AllowRequest(ip, "")
+	result, _ := limiter.AllowRequest(context.Background(), ip, "")
 	assert.False(t, result.Allowed)
 	assert.Equal(t, 120*time.Second, result.BlockTime)
 
 	// Verificar se o IP está bloqueado
-	blocked, _ := limiter.storage.IsBlocked(ip)
+	blocked, _ := limiter.storage.IsBlocked(ctx, ip)
 	assert.True(t, blocked)
+
+	// Continua bloqueado até o fim dos 120 segundos, informando o tempo que falta
+	clock.Advance(119 * time.Second)
+	result, _ = limiter.AllowRequest(ctx, ip, "")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.BlockTime)
+
+	// Depois do bloqueio (e da janela), o IP volta a ter o limite inteiro
+	clock.Advance(time.Second)
+	for i := 0; i < limiter.config.RateLimitPerIP; i++ {
+		result, _ := limiter.AllowRequest(ctx, ip, "")
+		assert.True(t, result.Allowed, "Requisição %d após o bloqueio deveria passar", i+1)
+	}
+	result, _ = limiter.AllowRequest(ctx, ip, "")
+	assert.False(t, result.Allowed)
 }
 
 func TestRateLimiter_TokenBlocking(t *testing.T) {
-	limiter := setupTestRateLimiter()
+	ctx := context.Background()
+	limiter, clock := setupTestRateLimiter()
 	token := "token123"
 
 	// Permitir requisições até o limite
 	for i := 0; i < limiter.config.RateLimitPerToken; i++ {
-		result := limiter.AllowRequest("", token)
+		result, _ := limiter.AllowRequest(context.Background(), "", token)
 		assert.True(t, result.Allowed)
 	}
 
 	// Atingiu o limite, deve bloquear
-	result := limiter.AllowRequest("", token)
+	result, _ := limiter.AllowRequest(context.Background(), "", token)
 	assert.False(t, result.Allowed)
 	assert.Equal(t, 300*time.Second, result.BlockTime)
 
 	// Verificar se o Token está bloqueado
-	blocked, _ := limiter.storage.IsBlocked(token)
+	blocked, _ := limiter.storage.IsBlocked(ctx, token)
 	assert.True(t, blocked)
+
+	clock.Advance(299 * time.Second)
+	result, _ = limiter.AllowRequest(ctx, "", token)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.BlockTime)
+
+	clock.Advance(time.Second)
+	result, _ = limiter.AllowRequest(ctx, "", token)
+	assert.True(t, result.Allowed, "O bloqueio de 300 segundos deveria ter expirado")
+}
+
+func TestRateLimiter_WindowRollover(t *testing.T) {
+	ctx := context.Background()
+	limiter, clock := setupTestRateLimiter()
+	ip := "10.0.0.2"
+
+	// Metade do limite no início da janela e o resto perto do fim
+	for i := 0; i < 2; i++ {
+		result, _ := limiter.AllowRequest(ctx, ip, "")
+		assert.True(t, result.Allowed)
+	}
+	clock.Advance(50 * time.Second)
+	for i := 0; i < 3; i++ {
+		result, _ := limiter.AllowRequest(ctx, ip, "")
+		assert.True(t, result.Allowed)
+	}
+
+	// A janela aberta na primeira requisição acaba aos 60 segundos e zera a contagem
+	clock.Advance(10 * time.Second)
+	for i := 0; i < limiter.config.RateLimitPerIP; i++ {
+		result, _ := limiter.AllowRequest(ctx, ip, "")
+		assert.True(t, result.Allowed, "Requisição %d da nova janela deveria passar", i+1)
+	}
+	result, _ := limiter.AllowRequest(ctx, ip, "")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 180*time.Second, result.BlockTime)
+}
+
+func TestRateLimiter_PenaltyEscalation(t *testing.T) {
+	ctx := context.Background()
+	limiter, clock := setupTestRateLimiter()
+	limiter.config.PenaltyIP = PenaltyPolicy{
+		Steps: []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute},
+		Decay: time.Hour,
+	}
+	ip := "10.0.0.9"
+
+	violate := func() RateLimitResult {
+		for i := 0; i < limiter.config.RateLimitPerIP; i++ {
+			result, _ := limiter.AllowRequest(ctx, ip, "")
+			assert.True(t, result.Allowed)
+		}
+		result, _ := limiter.AllowRequest(ctx, ip, "")
+		assert.False(t, result.Allowed)
+		return result
+	}
+
+	// Cada reincidência multiplica o bloqueio; depois da última etapa ela se repete
+	for _, want := range []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 30 * time.Minute} {
+		result := violate()
+		assert.Equal(t, want, result.BlockTime)
+		clock.Advance(want)
+	}
+
+	// Um período de decaimento inteiro sem violações volta à primeira etapa
+	clock.Advance(time.Hour)
+	assert.Equal(t, time.Minute, violate().BlockTime)
+
+	// Chaves com tempo específico não escalam
+	for i := 0; i < 3; i++ {
+		for j := 0; j < limiter.config.RateLimitPerIP; j++ {
+			limiter.AllowRequest(ctx, "192.168.1.1", "")
+		}
+		result, _ := limiter.AllowRequest(ctx, "192.168.1.1", "")
+		assert.Equal(t, 120*time.Second, result.BlockTime)
+		clock.Advance(120 * time.Second)
+	}
 }
 
 func TestRateLimiter_TokenTakesPriorityOverIP(t *testing.T) {
-	limiter := setupTestRateLimiter()
+	limiter, _ := setupTestRateLimiter()
 	ip := "192.168.1.1"
 	token := "token123"
 
 	// Atingir o limite do IP
 	for i := 0; i < limiter.config.RateLimitPerIP; i++ {
-		result := limiter.AllowRequest(ip, "")
+		result, _ := limiter.AllowRequest(context.Background(), ip, "")
 		assert.True(t, result.Allowed)
 	}
-	result := limiter.AllowRequest(ip, "")
+	result, _ := limiter.AllowRequest(context.Background(), ip, "")
 	assert.False(t, result.Allowed)
 
 	// Mas se um Token válido for enviado, a requisição deve passar até o limite do Token
 	for i := 0; i < limiter.config.RateLimitPerToken; i++ {
-		result := limiter.AllowRequest(ip, token)
+		result, _ := limiter.AllowRequest(context.Background(), ip, token)
+		assert.True(t, result.Allowed)
+	}
+	result, _ = limiter.AllowRequest(context.Background(), ip, token)
+	assert.False(t, result.Allowed)
+}
+
+func TestRateLimiter_RecordsMetrics(t *testing.T) {
+	m := metrics.New()
+	limiter, _ := setupTestRateLimiter()
+	limiter.WithMetrics(m)
+	ip := "10.0.0.1"
+
+	for i := 0; i < limiter.config.RateLimitPerIP; i++ {
+		limiter.AllowRequest(context.Background(), ip, "")
+	}
+	limiter.AllowRequest(context.Background(), ip, "")
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, w.Body.String(), `rate_limiter_decisions_total{decision="allowed",dimension="ip",policy="default"} 5`)
+	assert.Contains(t, w.Body.String(), `rate_limiter_decisions_total{decision="blocked",dimension="ip",policy="default"} 1`)
+}
+
+func TestRateLimiter_TracesDecision(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previousProvider) })
+	otel.SetTracerProvider(provider)
+
+	limiter, _ := setupTestRateLimiter()
+	limiter.AllowRequest(context.Background(), "10.0.0.2", "")
+
+	spans := recorder.Ended()
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	assert.ElementsMatch(t, []string{
+		"RateLimiterStorage.IsBlocked",
+		"RateLimiterStorage.IncrementRequest",
+		"RateLimiterService.AllowRequest",
+	}, names)
+
+	// O span principal deve carregar os atributos da decisão
+	root := spans[len(spans)-1]
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range root.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Equal(t, "allowed", attrs["rate_limiter.decision"].AsString())
+	assert.Equal(t, "ip", attrs["rate_limiter.dimension"].AsString())
+	assert.Equal(t, "default", attrs["rate_limiter.policy"].AsString())
+	assert.Equal(t, int64(4), attrs["rate_limiter.remaining"].AsInt64())
+}
+
+func TestRateLimiter_RecordResponseCountsMatchingStatuses(t *testing.T) {
+	ctx := context.Background()
+	limiter, clock := setupTestRateLimiter()
+	limiter.config.CountedStatusesIP, _ = ParseStatusFilter("4xx")
+	ip := "10.0.0.7"
+
+	// Com a contagem adiada, AllowRequest só consulta o bloqueio
+	for i := 0; i < 10; i++ {
+		result, _ := limiter.AllowRequest(ctx, ip, "")
+		assert.True(t, result.Allowed)
+		limiter.RecordResponse(ctx, ip, "", http.StatusOK)
+	}
+
+	for i := 0; i < 4; i++ {
+		result, _ := limiter.RecordResponse(ctx, ip, "", http.StatusNotFound)
+		assert.True(t, result.Allowed)
+	}
+
+	// A resposta que completa o limite bloqueia a chave para as próximas requisições
+	result, _ := limiter.RecordResponse(ctx, ip, "", http.StatusNotFound)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 180*time.Second, result.BlockTime)
+
+	result, _ = limiter.AllowRequest(ctx, ip, "")
+	assert.False(t, result.Allowed)
+
+	clock.Advance(180 * time.Second)
+	result, _ = limiter.AllowRequest(ctx, ip, "")
+	assert.True(t, result.Allowed)
+
+	// O token continua contando antes do handler
+	for i := 0; i < limiter.config.RateLimitPerToken; i++ {
+		result, _ := limiter.AllowRequest(ctx, ip, "token-abc")
+		assert.True(t, result.Allowed)
+		limiter.RecordResponse(ctx, ip, "token-abc", http.StatusNotFound)
+	}
+	result, _ = limiter.AllowRequest(ctx, ip, "token-abc")
+	assert.False(t, result.Allowed)
+}
+
+func TestRateLimiter_RecordResponseFailLocal(t *testing.T) {
+	ctx := context.Background()
+	limiter := setupFailingRateLimiter(FailLocal).WithClock(storagetest.NewClock())
+	limiter.config.CountedStatusesIP, _ = ParseStatusFilter("!5xx")
+
+	result, err := limiter.RecordResponse(ctx, "10.0.0.3", "", http.StatusOK)
+	assert.Error(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, FailLocal, result.FailureMode)
+
+	// O limite de 2 é completado no fallback local
+	result, _ = limiter.RecordResponse(ctx, "10.0.0.3", "", http.StatusOK)
+	assert.False(t, result.Allowed)
+
+	result, _ = limiter.AllowRequest(ctx, "10.0.0.3", "")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, FailLocal, result.FailureMode)
+}
+
+// failingStorage simula um storage fora do ar
+type failingStorage struct {
+	*storage.MemoryRateLimiterStorage
+	err error
+}
+
+func (f *failingStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	return false, f.err
+}
+
+func (f *failingStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return 0, f.err
+}
+
+func setupFailingRateLimiter(mode FailureMode) *RateLimiterService {
+	config.InitLogger()
+
+	return NewRateLimiterService(&failingStorage{
+		MemoryRateLimiterStorage: storage.NewMemoryStorage(),
+		err:                      errors.New("redis: connection refused"),
+	}, RateLimiterConfig{
+		RateLimitPerIP:     2,
+		RateLimitPerToken:  10,
+		DefaultBlockTimeIP: 60,
+		FailureModeIP:      mode,
+	}, config.Logger)
+}
+
+func TestRateLimiter_FailOpen(t *testing.T) {
+	limiter := setupFailingRateLimiter(FailOpen)
+
+	for i := 0; i < 5; i++ {
+		result, err := limiter.AllowRequest(context.Background(), "10.0.0.3", "")
+		assert.Error(t, err)
 		assert.True(t, result.Allowed)
+		assert.Equal(t, FailOpen, result.FailureMode)
 	}
-	result = limiter.AllowRequest(ip, token)
+}
+
+func TestRateLimiter_FailClosed(t *testing.T) {
+	limiter := setupFailingRateLimiter(FailClosed)
+
+	result, err := limiter.AllowRequest(context.Background(), "10.0.0.3", "")
+	assert.Error(t, err)
 	assert.False(t, result.Allowed)
+	assert.Equal(t, FailClosed, result.FailureMode)
+}
+
+func TestRateLimiter_FailLocal(t *testing.T) {
+	clock := storagetest.NewClock()
+	limiter := setupFailingRateLimiter(FailLocal).WithClock(clock)
+
+	// Os limites continuam valendo, agora contados em memória local
+	for i := 0; i < 2; i++ {
+		result, err := limiter.AllowRequest(context.Background(), "10.0.0.3", "")
+		assert.Error(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, FailLocal, result.FailureMode)
+	}
+
+	result, _ := limiter.AllowRequest(context.Background(), "10.0.0.3", "")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 60*time.Second, result.BlockTime)
+
+	// O bloqueio local expira como qualquer outro
+	clock.Advance(61 * time.Second)
+	result, _ = limiter.AllowRequest(context.Background(), "10.0.0.3", "")
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_FailureModeIsPerPolicy(t *testing.T) {
+	limiter := setupFailingRateLimiter(FailClosed)
+
+	// Sem modo configurado, o token mantém o fail-open
+	result, err := limiter.AllowRequest(context.Background(), "10.0.0.3", "token-sem-modo")
+	assert.Error(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestParseFailureMode(t *testing.T) {
+	for input, expected := range map[string]FailureMode{"": FailOpen, "open": FailOpen, "closed": FailClosed, "local": FailLocal} {
+		mode, ok := ParseFailureMode(input)
+		assert.True(t, ok)
+		assert.Equal(t, expected, mode)
+	}
+
+	_, ok := ParseFailureMode("panic")
+	assert.False(t, ok)
+}
+
+// stuckStorage simula um Redis travado: só retorna quando o contexto expira
+type stuckStorage struct {
+	*storage.MemoryRateLimiterStorage
+}
+
+func (s *stuckStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func TestRateLimiter_StorageTimeoutBudget(t *testing.T) {
+	config.InitLogger()
+	limiter := NewRateLimiterService(&stuckStorage{storage.NewMemoryStorage()}, RateLimiterConfig{
+		RateLimitPerIP: 5,
+		FailureModeIP:  FailClosed,
+		StorageTimeout: 20 * time.Millisecond,
+	}, config.Logger)
+
+	start := time.Now()
+	result, err := limiter.AllowRequest(context.Background(), "10.0.0.4", "")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second, "O orçamento do storage deve limitar a espera")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, FailClosed, result.FailureMode)
+}
+
+func TestRateLimiter_CanceledRequest(t *testing.T) {
+	config.InitLogger()
+	limiter := NewRateLimiterService(&stuckStorage{storage.NewMemoryStorage()}, RateLimiterConfig{
+		RateLimitPerIP: 5,
+		FailureModeIP:  FailClosed,
+	}, config.Logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Cancelamento do cliente não aciona o modo de falha
+	result, err := limiter.AllowRequest(ctx, "10.0.0.4", "")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, result.FailureMode)
 }
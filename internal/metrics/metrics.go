@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"container/heap"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rate_limiter"
+
+type Metrics struct {
+	registry       *prometheus.Registry
+	decisions      *prometheus.CounterVec
+	storageLatency *prometheus.HistogramVec
+	storageErrors  *prometheus.CounterVec
+	failureModes   *prometheus.CounterVec
+	checkLatency   prometheus.Histogram
+	evictions      *prometheus.CounterVec
+
+	// blocked é indexado pela chave e ordenado pela expiração em expiries, para que
+	// os bloqueios vencidos saiam a cada TrackBlock e não só quando há scrape.
+	mu       sync.Mutex
+	blocked  map[string]*blockedEntry
+	expiries blockedHeap
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "decisions_total",
+			Help:      "Decisões do rate limiter por resultado, dimensão e política.",
+		}, []string{"decision", "dimension", "policy"}),
+		storageLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Latência das operações do RateLimiterStorage.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_errors_total",
+			Help:      "Erros retornados pelo RateLimiterStorage por operação.",
+		}, []string{"operation"}),
+		failureModes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_failure_decisions_total",
+			Help:      "Decisões tomadas pelo modo de falha (open, closed, local) quando o storage falhou.",
+		}, []string{"dimension", "mode"}),
+		checkLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "middleware_check_duration_seconds",
+			Help:      "Tempo gasto pelo middleware para decidir se a requisição é permitida.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "memory_evictions_total",
+			Help:      "Chaves descartadas pelo armazenamento em memória ao atingir o limite (reason: idle ou blocked).",
+		}, []string{"reason"}),
+		blocked: make(map[string]*blockedEntry),
+	}
+
+	blockedKeys := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "blocked_keys",
+		Help:      "Chaves atualmente bloqueadas por esta instância.",
+	}, func() float64 {
+		return float64(m.blockedCount())
+	})
+
+	m.registry.MustRegister(
+		m.decisions,
+		m.storageLatency,
+		m.storageErrors,
+		m.failureModes,
+		m.checkLatency,
+		m.evictions,
+		blockedKeys,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+func (m *Metrics) ObserveDecision(dimension, policy string, allowed bool) {
+	if m == nil {
+		return
+	}
+	decision := "blocked"
+	if allowed {
+		decision = "allowed"
+	}
+	m.decisions.WithLabelValues(decision, dimension, policy).Inc()
+}
+
+func (m *Metrics) ObserveStorage(operation string, elapsed time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.storageLatency.WithLabelValues(operation).Observe(elapsed.Seconds())
+	if err != nil {
+		m.storageErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (m *Metrics) ObserveStorageFailure(dimension, mode string) {
+	if m == nil {
+		return
+	}
+	m.failureModes.WithLabelValues(dimension, mode).Inc()
+}
+
+func (m *Metrics) ObserveCheck(elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.checkLatency.Observe(elapsed.Seconds())
+}
+
+// ObserveEviction tem a assinatura de MemoryOptions.OnEvict.
+func (m *Metrics) ObserveEviction(blocked bool) {
+	if m == nil {
+		return
+	}
+	reason := "idle"
+	if blocked {
+		reason = "blocked"
+	}
+	m.evictions.WithLabelValues(reason).Inc()
+}
+
+func (m *Metrics) TrackBlock(key string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.purgeExpired(now)
+	if entry, exists := m.blocked[key]; exists {
+		entry.expiresAt = now.Add(duration)
+		heap.Fix(&m.expiries, entry.index)
+		return
+	}
+	entry := &blockedEntry{key: key, expiresAt: now.Add(duration)}
+	m.blocked[key] = entry
+	heap.Push(&m.expiries, entry)
+}
+
+func (m *Metrics) UntrackBlock(key string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, exists := m.blocked[key]; exists {
+		heap.Remove(&m.expiries, entry.index)
+		delete(m.blocked, key)
+	}
+}
+
+func (m *Metrics) blockedCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purgeExpired(time.Now())
+	return len(m.blocked)
+}
+
+func (m *Metrics) purgeExpired(now time.Time) {
+	for len(m.expiries) > 0 && now.After(m.expiries[0].expiresAt) {
+		entry := heap.Pop(&m.expiries).(*blockedEntry)
+		delete(m.blocked, entry.key)
+	}
+}
+
+type blockedEntry struct {
+	key       string
+	expiresAt time.Time
+	index     int
+}
+
+type blockedHeap []*blockedEntry
+
+func (h blockedHeap) Len() int           { return len(h) }
+func (h blockedHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h blockedHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *blockedHeap) Push(x any) {
+	entry := x.(*blockedEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+func (h *blockedHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_ObserveDecision(t *testing.T) {
+	m := New()
+
+	m.ObserveDecision("ip", "default", true)
+	m.ObserveDecision("ip", "default", true)
+	m.ObserveDecision("token", "custom", false)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.decisions.WithLabelValues("allowed", "ip", "default")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.decisions.WithLabelValues("blocked", "token", "custom")))
+}
+
+func TestMetrics_ObserveStorageErrors(t *testing.T) {
+	m := New()
+
+	m.ObserveStorage("IsBlocked", time.Millisecond, nil)
+	m.ObserveStorage("IsBlocked", time.Millisecond, errors.New("falha"))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("IsBlocked")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.storageLatency))
+}
+
+func TestMetrics_ObserveEviction(t *testing.T) {
+	m := New()
+
+	m.ObserveEviction(false)
+	m.ObserveEviction(false)
+	m.ObserveEviction(true)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.evictions.WithLabelValues("idle")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.evictions.WithLabelValues("blocked")))
+}
+
+func TestMetrics_BlockedKeysExpire(t *testing.T) {
+	m := New()
+
+	m.TrackBlock("192.168.1.1", time.Hour)
+	m.TrackBlock("192.168.1.2", -time.Second)
+
+	// Apenas o bloqueio ainda vigente deve ser contado
+	assert.Equal(t, 1, m.blockedCount())
+}
+
+func TestMetrics_BlockedKeysPurgedWithoutScrape(t *testing.T) {
+	m := New()
+
+	// Rotação de IPs sem nenhum scrape: os bloqueios vencidos saem a cada novo bloqueio
+	for i := 0; i < 1000; i++ {
+		m.TrackBlock(fmt.Sprintf("10.0.%d.%d", i/256, i%256), -time.Second)
+	}
+	m.TrackBlock("192.168.1.1", time.Hour)
+	m.TrackBlock("192.168.1.1", 2*time.Hour)
+
+	assert.Len(t, m.blocked, 1)
+	assert.Len(t, m.expiries, 1)
+
+	m.UntrackBlock("192.168.1.1")
+	assert.Empty(t, m.blocked)
+	assert.Empty(t, m.expiries)
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+
+	assert.NotPanics(t, func() {
+		m.ObserveDecision("ip", "default", true)
+		m.ObserveStorage("IsBlocked", time.Millisecond, nil)
+		m.ObserveCheck(time.Millisecond)
+		m.TrackBlock("key", time.Second)
+	})
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	m := New()
+	m.ObserveDecision("ip", "default", false)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `rate_limiter_decisions_total{decision="blocked",dimension="ip",policy="default"} 1`)
+	assert.Contains(t, w.Body.String(), "rate_limiter_blocked_keys")
+}
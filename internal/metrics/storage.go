@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"rate-limiter/internal/storage"
+	"time"
+)
+
+// InstrumentedStorage mede latência e erros de cada operação do storage decorado.
+type InstrumentedStorage struct {
+	next    storage.RateLimiterStorage
+	metrics *Metrics
+}
+
+func NewInstrumentedStorage(next storage.RateLimiterStorage, m *Metrics) *InstrumentedStorage {
+	return &InstrumentedStorage{next: next, metrics: m}
+}
+
+func (s *InstrumentedStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	start := time.Now()
+	count, err := s.next.IncrementRequest(ctx, key)
+	s.metrics.ObserveStorage("IncrementRequest", time.Since(start), err)
+	return count, err
+}
+
+func (s *InstrumentedStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	start := time.Now()
+	count, err := s.next.IncrementRequestBy(ctx, key, delta)
+	s.metrics.ObserveStorage("IncrementRequestBy", time.Since(start), err)
+	return count, err
+}
+
+func (s *InstrumentedStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	start := time.Now()
+	count, err := s.next.GetRequestCount(ctx, key)
+	s.metrics.ObserveStorage("GetRequestCount", time.Since(start), err)
+	return count, err
+}
+
+func (s *InstrumentedStorage) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	start := time.Now()
+	err := s.next.BlockKey(ctx, key, duration)
+	s.metrics.ObserveStorage("BlockKey", time.Since(start), err)
+	if err == nil {
+		s.metrics.TrackBlock(key, duration)
+	}
+	return err
+}
+
+func (s *InstrumentedStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	blocked, err := s.next.IsBlocked(ctx, key)
+	s.metrics.ObserveStorage("IsBlocked", time.Since(start), err)
+	return blocked, err
+}
+
+func (s *InstrumentedStorage) ResetKey(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.ResetKey(ctx, key)
+	s.metrics.ObserveStorage("ResetKey", time.Since(start), err)
+	return err
+}
+
+func (s *InstrumentedStorage) GetBlockDuration(ctx context.Context, key string) (time.Duration, error) {
+	start := time.Now()
+	duration, err := s.next.GetBlockDuration(ctx, key)
+	s.metrics.ObserveStorage("GetBlockDuration", time.Since(start), err)
+	return duration, err
+}
+
+func (s *InstrumentedStorage) GetBlockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	start := time.Now()
+	remaining, err := s.next.GetBlockRemaining(ctx, key)
+	s.metrics.ObserveStorage("GetBlockRemaining", time.Since(start), err)
+	return remaining, err
+}
+
+func (s *InstrumentedStorage) IncrementStrikes(ctx context.Context, key string, decay time.Duration) (int, error) {
+	start := time.Now()
+	strikes, err := s.next.IncrementStrikes(ctx, key, decay)
+	s.metrics.ObserveStorage("IncrementStrikes", time.Since(start), err)
+	return strikes, err
+}
+
+func (s *InstrumentedStorage) SetBlockDuration(ctx context.Context, key string, duration time.Duration) error {
+	start := time.Now()
+	err := s.next.SetBlockDuration(ctx, key, duration)
+	s.metrics.ObserveStorage("SetBlockDuration", time.Since(start), err)
+	return err
+}
+
+func (s *InstrumentedStorage) UnblockKey(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.next.UnblockKey(ctx, key)
+	s.metrics.ObserveStorage("UnblockKey", time.Since(start), err)
+	if err == nil {
+		s.metrics.UntrackBlock(key)
+	}
+	return err
+}
+
+func (s *InstrumentedStorage) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	keys, err := s.next.ListBlockedKeys(ctx)
+	s.metrics.ObserveStorage("ListBlockedKeys", time.Since(start), err)
+	return keys, err
+}
+
+func (s *InstrumentedStorage) InspectKey(ctx context.Context, key string) (storage.KeyInfo, error) {
+	start := time.Now()
+	info, err := s.next.InspectKey(ctx, key)
+	s.metrics.ObserveStorage("InspectKey", time.Since(start), err)
+	return info, err
+}
+
+func (s *InstrumentedStorage) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.next.Ping(ctx)
+	s.metrics.ObserveStorage("Ping", time.Since(start), err)
+	return err
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rate-limiter/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedStorage(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	s := NewInstrumentedStorage(storage.NewMemoryStorage(), m)
+
+	count, err := s.IncrementRequest(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	err = s.BlockKey(ctx, "192.168.1.1", time.Minute)
+	assert.NoError(t, err)
+
+	blocked, err := s.IsBlocked(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+
+	// Cada operação deve gerar uma série de latência própria
+	assert.Equal(t, 3, testutil.CollectAndCount(m.storageLatency))
+	assert.Equal(t, 1, m.blockedCount())
+}
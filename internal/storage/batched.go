@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type BatchedCounterOptions struct {
+	// FlushInterval é o intervalo entre envios dos deltas acumulados ao storage compartilhado.
+	FlushInterval time.Duration
+	// MaxPending é quantas requisições de uma chave cada instância pode contar sem
+	// sincronizar. Com N instâncias, a contagem vista fica no máximo N*(MaxPending-1)
+	// abaixo da real, que é o quanto o limite pode ser ultrapassado. 1 desliga o lote.
+	MaxPending int
+	// OnFlushError é chamado quando um envio periódico falha; os deltas são mantidos para o próximo.
+	OnFlushError func(key string, err error)
+}
+
+type batchedCounter struct {
+	base    int // última contagem global conhecida
+	pending int // requisições contadas localmente e ainda não enviadas
+	active  bool
+}
+
+// BatchedCounterStorage acumula os incrementos localmente e envia apenas os deltas
+// ao storage compartilhado, trocando precisão limitada por menos idas ao Redis.
+// As demais operações passam direto para o storage decorado.
+type BatchedCounterStorage struct {
+	RateLimiterStorage
+	opts BatchedCounterOptions
+
+	mu       sync.Mutex
+	counters map[string]*batchedCounter
+}
+
+func NewBatchedCounterStorage(next RateLimiterStorage, opts BatchedCounterOptions) *BatchedCounterStorage {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 100 * time.Millisecond
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = 1
+	}
+	return &BatchedCounterStorage{
+		RateLimiterStorage: next,
+		opts:               opts,
+		counters:           make(map[string]*batchedCounter),
+	}
+}
+
+func (b *BatchedCounterStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return b.IncrementRequestBy(ctx, key, 1)
+}
+
+func (b *BatchedCounterStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	b.mu.Lock()
+	counter, exists := b.counters[key]
+	if !exists {
+		counter = &batchedCounter{}
+		b.counters[key] = counter
+	}
+	counter.pending += delta
+	counter.active = true
+
+	// Chave nova ou cota local esgotada: sincroniza na hora para não contar em cima de uma base desconhecida
+	if !exists || counter.pending >= b.opts.MaxPending {
+		b.mu.Unlock()
+		return b.flushKey(ctx, key)
+	}
+
+	count := counter.base + counter.pending
+	b.mu.Unlock()
+	return count, nil
+}
+
+func (b *BatchedCounterStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	b.mu.Lock()
+	counter, exists := b.counters[key]
+	var pending int
+	if exists {
+		pending = counter.pending
+	}
+	b.mu.Unlock()
+
+	count, err := b.RateLimiterStorage.GetRequestCount(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return count + pending, nil
+}
+
+func (b *BatchedCounterStorage) ResetKey(ctx context.Context, key string) error {
+	b.mu.Lock()
+	delete(b.counters, key)
+	b.mu.Unlock()
+
+	return b.RateLimiterStorage.ResetKey(ctx, key)
+}
+
+// Flush envia todos os deltas pendentes e esquece as chaves sem tráfego desde o último envio,
+// para que a base delas seja relida do storage compartilhado na próxima requisição.
+func (b *BatchedCounterStorage) Flush(ctx context.Context) {
+	b.mu.Lock()
+	var keys []string
+	for key, counter := range b.counters {
+		if !counter.active {
+			delete(b.counters, key)
+			continue
+		}
+		counter.active = false
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+
+	for _, key := range keys {
+		if _, err := b.flushKey(ctx, key); err != nil && b.opts.OnFlushError != nil {
+			b.opts.OnFlushError(key, err)
+		}
+	}
+}
+
+// Run envia os deltas a cada FlushInterval até o contexto ser cancelado, quando faz um último envio.
+func (b *BatchedCounterStorage) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.Flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			b.Flush(ctx)
+		}
+	}
+}
+
+func (b *BatchedCounterStorage) flushKey(ctx context.Context, key string) (int, error) {
+	b.mu.Lock()
+	counter, exists := b.counters[key]
+	if !exists {
+		b.mu.Unlock()
+		return b.RateLimiterStorage.GetRequestCount(ctx, key)
+	}
+	delta := counter.pending
+	counter.pending = 0
+	b.mu.Unlock()
+
+	count, err := b.RateLimiterStorage.IncrementRequestBy(ctx, key, delta)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		counter.pending += delta
+		return 0, err
+	}
+	counter.base = count
+	return count + counter.pending, nil
+}
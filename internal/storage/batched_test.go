@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchedCounter_AccumulatesLocallyUntilFlush(t *testing.T) {
+	ctx := context.Background()
+	shared := NewMemoryStorage()
+	batched := NewBatchedCounterStorage(shared, BatchedCounterOptions{MaxPending: 10})
+
+	for i := 1; i <= 5; i++ {
+		count, err := batched.IncrementRequest(ctx, "ip:1")
+		assert.NoError(t, err)
+		assert.Equal(t, i, count)
+	}
+
+	// Só a primeira requisição (chave nova) foi sincronizada
+	count, _ := shared.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 1, count)
+
+	batched.Flush(ctx)
+	count, _ = shared.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 5, count)
+}
+
+func TestBatchedCounter_FlushesWhenLocalShareIsExhausted(t *testing.T) {
+	ctx := context.Background()
+	shared := NewMemoryStorage()
+	batched := NewBatchedCounterStorage(shared, BatchedCounterOptions{MaxPending: 3})
+
+	for i := 0; i < 4; i++ {
+		_, _ = batched.IncrementRequest(ctx, "ip:1")
+	}
+
+	count, _ := shared.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 4, count)
+}
+
+func TestBatchedCounter_MaxPendingOneIsExact(t *testing.T) {
+	ctx := context.Background()
+	shared := NewMemoryStorage()
+	a := NewBatchedCounterStorage(shared, BatchedCounterOptions{MaxPending: 1})
+	b := NewBatchedCounterStorage(shared, BatchedCounterOptions{MaxPending: 1})
+
+	for i := 1; i <= 10; i++ {
+		node := a
+		if i%2 == 0 {
+			node = b
+		}
+		count, err := node.IncrementRequest(ctx, "ip:1")
+		assert.NoError(t, err)
+		assert.Equal(t, i, count)
+	}
+}
+
+func TestBatchedCounter_OvershootIsBounded(t *testing.T) {
+	ctx := context.Background()
+	const (
+		limit      = 50
+		instances  = 4
+		maxPending = 5
+		requests   = 400
+	)
+
+	shared := NewMemoryStorage()
+	nodes := make([]*BatchedCounterStorage, instances)
+	for i := range nodes {
+		nodes[i] = NewBatchedCounterStorage(shared, BatchedCounterOptions{MaxPending: maxPending})
+	}
+
+	allowed := 0
+	for i := 0; i < requests; i++ {
+		count, err := nodes[i%instances].IncrementRequest(ctx, "ip:1")
+		assert.NoError(t, err)
+		if count <= limit {
+			allowed++
+		}
+	}
+
+	assert.GreaterOrEqual(t, allowed, limit)
+	assert.LessOrEqual(t, allowed, limit+instances*(maxPending-1), "Overshoot acima do limite configurado")
+
+	// Depois do envio final nenhuma requisição se perde
+	for _, node := range nodes {
+		node.Flush(ctx)
+	}
+	count, _ := shared.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, requests, count)
+}
+
+func TestBatchedCounter_ResetDropsPendingDelta(t *testing.T) {
+	ctx := context.Background()
+	shared := NewMemoryStorage()
+	batched := NewBatchedCounterStorage(shared, BatchedCounterOptions{MaxPending: 10})
+
+	for i := 0; i < 3; i++ {
+		_, _ = batched.IncrementRequest(ctx, "ip:1")
+	}
+	assert.NoError(t, batched.ResetKey(ctx, "ip:1"))
+	batched.Flush(ctx)
+
+	count, _ := shared.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 0, count)
+	count, _ = batched.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 0, count)
+}
+
+func TestBatchedCounter_RunFlushesPeriodicallyAndOnStop(t *testing.T) {
+	shared := NewMemoryStorage()
+	batched := NewBatchedCounterStorage(shared, BatchedCounterOptions{FlushInterval: 10 * time.Millisecond, MaxPending: 100})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		batched.Run(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		_, _ = batched.IncrementRequest(context.Background(), "ip:1")
+	}
+	assert.Eventually(t, func() bool {
+		count, _ := shared.GetRequestCount(context.Background(), "ip:1")
+		return count == 3
+	}, time.Second, 5*time.Millisecond)
+
+	_, _ = batched.IncrementRequest(context.Background(), "ip:1")
+	cancel()
+	<-done
+
+	count, _ := shared.GetRequestCount(context.Background(), "ip:1")
+	assert.Equal(t, 4, count)
+}
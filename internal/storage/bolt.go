@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltCountersBucket = []byte("counters")
+	boltBlocksBucket   = []byte("blocks")
+	boltStrikesBucket  = []byte("strikes")
+)
+
+// boltEntry é o valor gravado nos dois buckets: contagem (ou duração do bloqueio,
+// em nanossegundos) e o instante de expiração em Unix nanossegundos.
+type boltEntry struct {
+	value     int64
+	expiresAt int64
+}
+
+func (e boltEntry) encode() []byte {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf[:8], uint64(e.value))
+	binary.BigEndian.PutUint64(buf[8:], uint64(e.expiresAt))
+	return buf
+}
+
+func decodeBoltEntry(data []byte) (boltEntry, bool) {
+	if len(data) != 16 {
+		return boltEntry{}, false
+	}
+	return boltEntry{
+		value:     int64(binary.BigEndian.Uint64(data[:8])),
+		expiresAt: int64(binary.BigEndian.Uint64(data[8:])),
+	}, true
+}
+
+func (e boltEntry) remaining(now time.Time) time.Duration {
+	return time.Duration(e.expiresAt - now.UnixNano())
+}
+
+// BoltRateLimiterStorage guarda contadores e bloqueios em um arquivo bbolt local,
+// para que reiniciar o serviço não desbloqueie ninguém. Cada escrita é uma transação
+// com fsync, então o arquivo sobrevive a quedas do processo sem corromper.
+type BoltRateLimiterStorage struct {
+	db    *bolt.DB
+	clock Clock
+}
+
+type BoltOptions struct {
+	// Clock substitui o relógio real; usado pelos testes.
+	Clock Clock
+}
+
+func NewBoltStorage(path string) (*BoltRateLimiterStorage, error) {
+	return NewBoltStorageWithOptions(path, BoltOptions{})
+}
+
+func NewBoltStorageWithOptions(path string, opts BoltOptions) (*BoltRateLimiterStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	// O timeout evita travar para sempre se outro processo estiver com o arquivo aberto
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltCountersBucket, boltBlocksBucket, boltStrikesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltRateLimiterStorage{db: db, clock: clockOrSystem(opts.Clock)}, nil
+}
+
+func (b *BoltRateLimiterStorage) Close() error {
+	return b.db.Close()
+}
+
+func (b *BoltRateLimiterStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return b.IncrementRequestBy(ctx, key, 1)
+}
+
+func (b *BoltRateLimiterStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	var count int64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltCountersBucket)
+		now := b.clock.Now()
+
+		// Mesma janela fixa do Redis: a expiração só é definida na abertura da janela
+		entry, exists := decodeBoltEntry(bucket.Get([]byte(key)))
+		if !exists || entry.remaining(now) <= 0 {
+			entry = boltEntry{expiresAt: now.Add(counterWindow).UnixNano()}
+		}
+		entry.value += int64(delta)
+		count = entry.value
+		return bucket.Put([]byte(key), entry.encode())
+	})
+	return int(count), err
+}
+
+func (b *BoltRateLimiterStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	entry, exists, err := b.get(boltCountersBucket, key)
+	if err != nil || !exists {
+		return 0, err
+	}
+	return int(entry.value), nil
+}
+
+func (b *BoltRateLimiterStorage) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	entry := boltEntry{value: int64(duration), expiresAt: b.clock.Now().Add(duration).UnixNano()}
+	return b.put(boltBlocksBucket, key, entry)
+}
+
+func (b *BoltRateLimiterStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	_, exists, err := b.get(boltBlocksBucket, key)
+	return exists, err
+}
+
+func (b *BoltRateLimiterStorage) ResetKey(ctx context.Context, key string) error {
+	return b.delete(boltCountersBucket, key)
+}
+
+func (b *BoltRateLimiterStorage) GetBlockDuration(ctx context.Context, key string) (time.Duration, error) {
+	var duration time.Duration
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if entry, exists := decodeBoltEntry(tx.Bucket(boltBlocksBucket).Get([]byte(key))); exists && entry.remaining(b.clock.Now()) > 0 {
+			duration = time.Duration(entry.value)
+		}
+		return nil
+	})
+	return duration, err
+}
+
+func (b *BoltRateLimiterStorage) GetBlockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	var remaining time.Duration
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if entry, exists := decodeBoltEntry(tx.Bucket(boltBlocksBucket).Get([]byte(key))); exists {
+			remaining = max(entry.remaining(b.clock.Now()), 0)
+		}
+		return nil
+	})
+	return remaining, err
+}
+
+// SetBlockDuration grava a duração sem bloquear, preservando a expiração de um bloqueio existente.
+func (b *BoltRateLimiterStorage) SetBlockDuration(ctx context.Context, key string, duration time.Duration) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBlocksBucket)
+		entry, exists := decodeBoltEntry(bucket.Get([]byte(key)))
+		if !exists {
+			entry.expiresAt = b.clock.Now().UnixNano()
+		}
+		entry.value = int64(duration)
+		return bucket.Put([]byte(key), entry.encode())
+	})
+}
+
+// IncrementStrikes soma uma violação e adia o esquecimento para decay a partir de agora.
+func (b *BoltRateLimiterStorage) IncrementStrikes(ctx context.Context, key string, decay time.Duration) (int, error) {
+	var strikes int64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltStrikesBucket)
+		now := b.clock.Now()
+
+		entry, exists := decodeBoltEntry(bucket.Get([]byte(key)))
+		if !exists || entry.remaining(now) <= 0 {
+			entry = boltEntry{}
+		}
+		entry.value++
+		entry.expiresAt = now.Add(decay).UnixNano()
+		strikes = entry.value
+		return bucket.Put([]byte(key), entry.encode())
+	})
+	return int(strikes), err
+}
+
+func (b *BoltRateLimiterStorage) UnblockKey(ctx context.Context, key string) error {
+	return b.delete(boltBlocksBucket, key)
+}
+
+func (b *BoltRateLimiterStorage) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	keys := []string{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		now := b.clock.Now()
+		return tx.Bucket(boltBlocksBucket).ForEach(func(k, v []byte) error {
+			if entry, ok := decodeBoltEntry(v); ok && entry.remaining(now) > 0 {
+				keys = append(keys, string(k))
+			}
+			return nil
+		})
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (b *BoltRateLimiterStorage) InspectKey(ctx context.Context, key string) (KeyInfo, error) {
+	info := KeyInfo{Key: key}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		now := b.clock.Now()
+		if entry, ok := decodeBoltEntry(tx.Bucket(boltCountersBucket).Get([]byte(key))); ok && entry.remaining(now) > 0 {
+			info.Count = int(entry.value)
+			info.CountTTL = entry.remaining(now)
+		}
+		if entry, ok := decodeBoltEntry(tx.Bucket(boltBlocksBucket).Get([]byte(key))); ok && entry.remaining(now) > 0 {
+			info.Blocked = true
+			info.BlockRemaining = entry.remaining(now)
+		}
+		return nil
+	})
+	return info, err
+}
+
+// Ping confirma que o arquivo continua legível.
+func (b *BoltRateLimiterStorage) Ping(ctx context.Context) error {
+	return b.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
+// Cleanup remove contadores, bloqueios e violações expirados. As leituras já ignoram entradas
+// expiradas; a limpeza existe apenas para o arquivo não crescer sem limite.
+func (b *BoltRateLimiterStorage) Cleanup(ctx context.Context) (int, error) {
+	removed := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		now := b.clock.Now()
+		for _, name := range [][]byte{boltCountersBucket, boltBlocksBucket, boltStrikesBucket} {
+			bucket := tx.Bucket(name)
+			// Coleta antes de apagar: remover durante a iteração faz o cursor pular chaves
+			var expired [][]byte
+			err := bucket.ForEach(func(k, v []byte) error {
+				if entry, ok := decodeBoltEntry(v); !ok || entry.remaining(now) <= 0 {
+					expired = append(expired, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, k := range expired {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			removed += len(expired)
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// RunCleanup executa Cleanup a cada intervalo até o contexto ser cancelado.
+// Intervalos não positivos usam defaultCleanupInterval.
+func (b *BoltRateLimiterStorage) RunCleanup(ctx context.Context, interval time.Duration, onError func(error)) {
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := b.Cleanup(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// get devolve a entrada apenas se ainda não expirou.
+func (b *BoltRateLimiterStorage) get(bucket []byte, key string) (boltEntry, bool, error) {
+	var entry boltEntry
+	var exists bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		entry, exists = decodeBoltEntry(tx.Bucket(bucket).Get([]byte(key)))
+		if exists && entry.remaining(b.clock.Now()) <= 0 {
+			exists = false
+		}
+		return nil
+	})
+	return entry, exists, err
+}
+
+func (b *BoltRateLimiterStorage) put(bucket []byte, key string, entry boltEntry) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), entry.encode())
+	})
+}
+
+func (b *BoltRateLimiterStorage) delete(bucket []byte, key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+}
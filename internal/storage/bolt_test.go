@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBoltStorage(t *testing.T) (*BoltRateLimiterStorage, string) {
+	return setupBoltStorageWithClock(t, SystemClock)
+}
+
+func setupBoltStorageWithClock(t *testing.T, clock Clock) (*BoltRateLimiterStorage, string) {
+	path := filepath.Join(t.TempDir(), "rate-limiter.db")
+	boltStorage, err := NewBoltStorageWithOptions(path, BoltOptions{Clock: clock})
+	assert.NoError(t, err)
+	t.Cleanup(func() { boltStorage.Close() })
+	return boltStorage, path
+}
+
+func TestBolt_IncrementRequest(t *testing.T) {
+	ctx := context.Background()
+	boltStorage, _ := setupBoltStorage(t)
+
+	count, err := boltStorage.IncrementRequest(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = boltStorage.IncrementRequestBy(ctx, "ip:1", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	count, _ = boltStorage.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 5, count)
+
+	assert.NoError(t, boltStorage.ResetKey(ctx, "ip:1"))
+	count, _ = boltStorage.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 0, count)
+}
+
+func TestBolt_BlockKey(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	boltStorage, _ := setupBoltStorageWithClock(t, clock)
+
+	assert.NoError(t, boltStorage.BlockKey(ctx, "ip:1", 50*time.Millisecond))
+
+	blocked, err := boltStorage.IsBlocked(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+
+	duration, err := boltStorage.GetBlockDuration(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, duration)
+
+	clock.Advance(80 * time.Millisecond)
+	blocked, _ = boltStorage.IsBlocked(ctx, "ip:1")
+	assert.False(t, blocked, "A chave deveria ter sido desbloqueada após o tempo definido")
+}
+
+func TestBolt_StatePersistsAcrossRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rate-limiter.db")
+
+	boltStorage, err := NewBoltStorage(path)
+	assert.NoError(t, err)
+	_, _ = boltStorage.IncrementRequestBy(ctx, "ip:1", 3)
+	assert.NoError(t, boltStorage.BlockKey(ctx, "ip:1", time.Minute))
+	assert.NoError(t, boltStorage.Close())
+
+	// Reiniciar o serviço não pode desbloquear ninguém
+	reopened, err := NewBoltStorage(path)
+	assert.NoError(t, err)
+	defer reopened.Close()
+
+	blocked, _ := reopened.IsBlocked(ctx, "ip:1")
+	assert.True(t, blocked)
+	count, _ := reopened.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 3, count)
+	duration, _ := reopened.GetBlockDuration(ctx, "ip:1")
+	assert.Equal(t, time.Minute, duration)
+}
+
+func TestBolt_InspectAndUnblock(t *testing.T) {
+	ctx := context.Background()
+	boltStorage, _ := setupBoltStorage(t)
+
+	_, _ = boltStorage.IncrementRequest(ctx, "ip:1")
+	assert.NoError(t, boltStorage.BlockKey(ctx, "ip:1", time.Minute))
+	assert.NoError(t, boltStorage.BlockKey(ctx, "ip:0", time.Minute))
+
+	info, err := boltStorage.InspectKey(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.Count)
+	assert.True(t, info.CountTTL > 0 && info.CountTTL <= time.Minute)
+	assert.True(t, info.Blocked)
+	assert.True(t, info.BlockRemaining > 0 && info.BlockRemaining <= time.Minute)
+
+	keys, err := boltStorage.ListBlockedKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ip:0", "ip:1"}, keys)
+
+	assert.NoError(t, boltStorage.UnblockKey(ctx, "ip:1"))
+	blocked, _ := boltStorage.IsBlocked(ctx, "ip:1")
+	assert.False(t, blocked)
+}
+
+func TestBolt_CleanupRemovesExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	boltStorage, _ := setupBoltStorageWithClock(t, clock)
+
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NoError(t, boltStorage.BlockKey(ctx, key, 10*time.Millisecond))
+	}
+	assert.NoError(t, boltStorage.BlockKey(ctx, "active", time.Minute))
+	_, _ = boltStorage.IncrementRequest(ctx, "active")
+	clock.Advance(30 * time.Millisecond)
+
+	removed, err := boltStorage.Cleanup(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+
+	keys, _ := boltStorage.ListBlockedKeys(ctx)
+	assert.Equal(t, []string{"active"}, keys)
+	count, _ := boltStorage.GetRequestCount(ctx, "active")
+	assert.Equal(t, 1, count)
+}
+
+func TestBolt_RunCleanupWithoutInterval(t *testing.T) {
+	boltStorage, _ := setupBoltStorage(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Intervalo zero usa o padrão em vez de entrar em pânico no time.NewTicker
+	assert.NotPanics(t, func() { boltStorage.RunCleanup(ctx, 0, nil) })
+}
+
+func TestBolt_Ping(t *testing.T) {
+	boltStorage, _ := setupBoltStorage(t)
+	assert.NoError(t, boltStorage.Ping(context.Background()))
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+type BlockCacheOptions struct {
+	// MaxTTL limita por quanto tempo um bloqueio fica no cache local, para que uma
+	// invalidação perdida não mantenha a chave bloqueada até o fim do bloqueio.
+	MaxTTL time.Duration
+	// MaxEntries limita o tamanho do cache; acima dele novos bloqueios não são cacheados.
+	MaxEntries int
+	// Clock substitui o relógio real; usado pelos testes.
+	Clock Clock
+}
+
+// InvalidationSubscriber é implementado por storages capazes de avisar as demais
+// instâncias quando um bloqueio é removido (ex.: pub/sub do Redis).
+type InvalidationSubscriber interface {
+	SubscribeInvalidations(ctx context.Context, handler func(key string)) error
+}
+
+type cachedBlock struct {
+	key         string
+	cachedUntil time.Time
+	expiresAt   time.Time
+	duration    time.Duration
+	index       int
+}
+
+// BlockCacheStorage lembra localmente das chaves bloqueadas até o fim do bloqueio,
+// de forma que tráfego de uma chave já bloqueada seja rejeitado sem ir ao Redis.
+// Apenas decisões positivas são cacheadas: chaves livres sempre consultam o storage.
+type BlockCacheStorage struct {
+	RateLimiterStorage
+	opts BlockCacheOptions
+
+	// expiries ordena as entradas por cachedUntil, para que as vencidas saiam em
+	// O(log n) a cada inserção em vez de uma varredura do mapa inteiro.
+	mu       sync.RWMutex
+	blocks   map[string]*cachedBlock
+	expiries cacheHeap
+}
+
+func NewBlockCacheStorage(next RateLimiterStorage, opts BlockCacheOptions) *BlockCacheStorage {
+	if opts.MaxTTL <= 0 {
+		opts.MaxTTL = time.Minute
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 100000
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	return &BlockCacheStorage{
+		RateLimiterStorage: next,
+		opts:               opts,
+		blocks:             make(map[string]*cachedBlock),
+	}
+}
+
+func (c *BlockCacheStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	if _, hit := c.lookup(key); hit {
+		return true, nil
+	}
+
+	blocked, err := c.RateLimiterStorage.IsBlocked(ctx, key)
+	if err != nil || !blocked {
+		return blocked, err
+	}
+
+	// Bloqueio criado por outra instância: descobre quanto falta para cachear até o fim
+	info, err := c.RateLimiterStorage.InspectKey(ctx, key)
+	if err == nil && info.BlockRemaining > 0 {
+		c.store(key, info.BlockRemaining, 0)
+	}
+	return true, nil
+}
+
+func (c *BlockCacheStorage) GetBlockDuration(ctx context.Context, key string) (time.Duration, error) {
+	if block, hit := c.lookup(key); hit && block.duration > 0 {
+		return block.duration, nil
+	}
+
+	duration, err := c.RateLimiterStorage.GetBlockDuration(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	if block, exists := c.blocks[key]; exists {
+		block.duration = duration
+	}
+	c.mu.Unlock()
+	return duration, nil
+}
+
+func (c *BlockCacheStorage) GetBlockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	if block, hit := c.lookup(key); hit {
+		if remaining := block.expiresAt.Sub(c.opts.Clock.Now()); remaining > 0 {
+			return remaining, nil
+		}
+	}
+	return c.RateLimiterStorage.GetBlockRemaining(ctx, key)
+}
+
+func (c *BlockCacheStorage) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	if err := c.RateLimiterStorage.BlockKey(ctx, key, duration); err != nil {
+		return err
+	}
+	c.store(key, duration, duration)
+	return nil
+}
+
+func (c *BlockCacheStorage) SetBlockDuration(ctx context.Context, key string, duration time.Duration) error {
+	if err := c.RateLimiterStorage.SetBlockDuration(ctx, key, duration); err != nil {
+		return err
+	}
+
+	// Só a duração registrada muda; a expiração do bloqueio em cache continua a mesma
+	c.mu.Lock()
+	if block, exists := c.blocks[key]; exists {
+		block.duration = duration
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *BlockCacheStorage) UnblockKey(ctx context.Context, key string) error {
+	c.Invalidate(key)
+	return c.RateLimiterStorage.UnblockKey(ctx, key)
+}
+
+// Invalidate remove a chave do cache local. É o handler das invalidações recebidas via pub/sub.
+func (c *BlockCacheStorage) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if block, exists := c.blocks[key]; exists {
+		heap.Remove(&c.expiries, block.index)
+		delete(c.blocks, key)
+	}
+}
+
+func (c *BlockCacheStorage) lookup(key string) (cachedBlock, bool) {
+	c.mu.RLock()
+	entry, exists := c.blocks[key]
+	var block cachedBlock
+	if exists {
+		block = *entry
+	}
+	c.mu.RUnlock()
+
+	if !exists {
+		return cachedBlock{}, false
+	}
+	if c.opts.Clock.Now().After(block.cachedUntil) {
+		c.Invalidate(key)
+		return cachedBlock{}, false
+	}
+	return block, true
+}
+
+func (c *BlockCacheStorage) store(key string, remaining, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.opts.Clock.Now()
+	cachedUntil, expiresAt := now.Add(min(remaining, c.opts.MaxTTL)), now.Add(remaining)
+	if block, exists := c.blocks[key]; exists {
+		block.cachedUntil, block.expiresAt, block.duration = cachedUntil, expiresAt, duration
+		heap.Fix(&c.expiries, block.index)
+		return
+	}
+
+	for len(c.expiries) > 0 && now.After(c.expiries[0].cachedUntil) {
+		expired := heap.Pop(&c.expiries).(*cachedBlock)
+		delete(c.blocks, expired.key)
+	}
+	if len(c.blocks) >= c.opts.MaxEntries {
+		return
+	}
+
+	block := &cachedBlock{key: key, cachedUntil: cachedUntil, expiresAt: expiresAt, duration: duration}
+	c.blocks[key] = block
+	heap.Push(&c.expiries, block)
+}
+
+type cacheHeap []*cachedBlock
+
+func (h cacheHeap) Len() int           { return len(h) }
+func (h cacheHeap) Less(i, j int) bool { return h[i].cachedUntil.Before(h[j].cachedUntil) }
+func (h cacheHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *cacheHeap) Push(x any) {
+	block := x.(*cachedBlock)
+	block.index = len(*h)
+	*h = append(*h, block)
+}
+func (h *cacheHeap) Pop() any {
+	old := *h
+	block := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return block
+}
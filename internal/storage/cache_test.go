@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStorage conta as consultas de bloqueio que chegam ao storage compartilhado
+type countingStorage struct {
+	*MemoryRateLimiterStorage
+	mu           sync.Mutex
+	blockedCalls int
+}
+
+func (c *countingStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	c.blockedCalls++
+	c.mu.Unlock()
+	return c.MemoryRateLimiterStorage.IsBlocked(ctx, key)
+}
+
+func (c *countingStorage) calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blockedCalls
+}
+
+func TestBlockCache_ServesBlockedKeysLocally(t *testing.T) {
+	ctx := context.Background()
+	shared := &countingStorage{MemoryRateLimiterStorage: NewMemoryStorage()}
+	cache := NewBlockCacheStorage(shared, BlockCacheOptions{})
+
+	assert.NoError(t, cache.BlockKey(ctx, "ip:1", time.Minute))
+
+	for i := 0; i < 10; i++ {
+		blocked, err := cache.IsBlocked(ctx, "ip:1")
+		assert.NoError(t, err)
+		assert.True(t, blocked)
+	}
+	duration, err := cache.GetBlockDuration(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, duration)
+
+	// Nenhuma consulta deve ter chegado ao storage compartilhado
+	assert.Equal(t, 0, shared.calls())
+}
+
+func TestBlockCache_CachesBlocksCreatedByOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	shared := &countingStorage{MemoryRateLimiterStorage: NewMemoryStorage()}
+	cache := NewBlockCacheStorage(shared, BlockCacheOptions{})
+
+	// Outra instância bloqueia a chave direto no storage compartilhado
+	assert.NoError(t, shared.BlockKey(ctx, "ip:1", time.Minute))
+
+	for i := 0; i < 5; i++ {
+		blocked, err := cache.IsBlocked(ctx, "ip:1")
+		assert.NoError(t, err)
+		assert.True(t, blocked)
+	}
+	assert.Equal(t, 1, shared.calls())
+}
+
+func TestBlockCache_DoesNotCacheUnblockedKeys(t *testing.T) {
+	ctx := context.Background()
+	shared := &countingStorage{MemoryRateLimiterStorage: NewMemoryStorage()}
+	cache := NewBlockCacheStorage(shared, BlockCacheOptions{})
+
+	blocked, _ := cache.IsBlocked(ctx, "ip:1")
+	assert.False(t, blocked)
+
+	assert.NoError(t, shared.BlockKey(ctx, "ip:1", time.Minute))
+	blocked, _ = cache.IsBlocked(ctx, "ip:1")
+	assert.True(t, blocked, "Bloqueio criado depois de uma consulta negativa deve ser visto")
+}
+
+func TestBlockCache_InvalidateDropsLocalBlock(t *testing.T) {
+	ctx := context.Background()
+	shared := NewMemoryStorage()
+	cache := NewBlockCacheStorage(shared, BlockCacheOptions{})
+
+	assert.NoError(t, cache.BlockKey(ctx, "ip:1", time.Minute))
+
+	// Admin desbloqueia por outra instância; a invalidação chega via pub/sub
+	assert.NoError(t, shared.UnblockKey(ctx, "ip:1"))
+	cache.Invalidate("ip:1")
+
+	blocked, err := cache.IsBlocked(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.False(t, blocked)
+}
+
+func TestBlockCache_UnblockKeyClearsLocalEntry(t *testing.T) {
+	ctx := context.Background()
+	cache := NewBlockCacheStorage(NewMemoryStorage(), BlockCacheOptions{})
+
+	assert.NoError(t, cache.BlockKey(ctx, "ip:1", time.Minute))
+	assert.NoError(t, cache.UnblockKey(ctx, "ip:1"))
+
+	blocked, _ := cache.IsBlocked(ctx, "ip:1")
+	assert.False(t, blocked)
+}
+
+func TestBlockCache_EntriesExpireWithBlock(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	cache := NewBlockCacheStorage(NewMemoryStorageWithOptions(MemoryOptions{Clock: clock}), BlockCacheOptions{Clock: clock})
+
+	assert.NoError(t, cache.BlockKey(ctx, "ip:1", 50*time.Millisecond))
+	clock.Advance(80 * time.Millisecond)
+
+	blocked, _ := cache.IsBlocked(ctx, "ip:1")
+	assert.False(t, blocked)
+}
+
+func TestBlockCache_MaxTTLForcesRevalidation(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	shared := &countingStorage{MemoryRateLimiterStorage: NewMemoryStorageWithOptions(MemoryOptions{Clock: clock})}
+	cache := NewBlockCacheStorage(shared, BlockCacheOptions{MaxTTL: 30 * time.Millisecond, Clock: clock})
+
+	assert.NoError(t, cache.BlockKey(ctx, "ip:1", time.Minute))
+	// Invalidação perdida: o desbloqueio não passou pelo cache
+	assert.NoError(t, shared.UnblockKey(ctx, "ip:1"))
+
+	blocked, _ := cache.IsBlocked(ctx, "ip:1")
+	assert.True(t, blocked)
+
+	clock.Advance(50 * time.Millisecond)
+	blocked, _ = cache.IsBlocked(ctx, "ip:1")
+	assert.False(t, blocked)
+}
+
+func TestBlockCache_RespectsMaxEntries(t *testing.T) {
+	ctx := context.Background()
+	shared := &countingStorage{MemoryRateLimiterStorage: NewMemoryStorage()}
+	cache := NewBlockCacheStorage(shared, BlockCacheOptions{MaxEntries: 1})
+
+	assert.NoError(t, cache.BlockKey(ctx, "ip:1", time.Minute))
+	assert.NoError(t, cache.BlockKey(ctx, "ip:2", time.Minute))
+
+	blocked, _ := cache.IsBlocked(ctx, "ip:2")
+	assert.True(t, blocked)
+	assert.Equal(t, 1, shared.calls(), "ip:2 não cabe no cache e deve consultar o storage")
+}
+
+func TestBlockCache_ExpiredEntriesMakeRoom(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	cache := NewBlockCacheStorage(NewMemoryStorageWithOptions(MemoryOptions{Clock: clock}), BlockCacheOptions{MaxEntries: 2, Clock: clock})
+
+	assert.NoError(t, cache.BlockKey(ctx, "ip:1", time.Second))
+	assert.NoError(t, cache.BlockKey(ctx, "ip:2", time.Hour))
+	clock.Advance(2 * time.Second)
+
+	// Cheio, o cache descarta só o que já venceu, na ordem do heap de expiração
+	assert.NoError(t, cache.BlockKey(ctx, "ip:3", time.Hour))
+	assert.Len(t, cache.blocks, 2)
+	assert.Len(t, cache.expiries, 2)
+	assert.Contains(t, cache.blocks, "ip:3")
+
+	assert.NoError(t, cache.UnblockKey(ctx, "ip:2"))
+	assert.Len(t, cache.expiries, 1)
+}
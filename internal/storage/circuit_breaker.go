@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type CircuitBreakerOptions struct {
+	// FailureThreshold é o número de falhas consecutivas que abre o circuito.
+	FailureThreshold int
+	// LatencyThreshold faz chamadas mais lentas contarem como falha (zero desativa).
+	LatencyThreshold time.Duration
+	// OpenTimeout é quanto tempo o circuito fica aberto antes de testar o primário.
+	OpenTimeout time.Duration
+	// HalfOpenProbes é o número de chamadas de teste bem-sucedidas para fechar o circuito.
+	HalfOpenProbes int
+	// OnStateChange é chamado fora do lock a cada transição de estado.
+	OnStateChange func(from, to CircuitState)
+	// Clock substitui o relógio real; usado pelos testes.
+	Clock Clock
+}
+
+// CircuitBreakerStorage protege um storage remoto (tipicamente o Redis). Enquanto
+// o circuito está aberto todas as operações vão para o fallback sem tocar no
+// primário; no half-open apenas HalfOpenProbes chamadas testam o primário.
+// A chamada que falha no primário devolve o erro para que o serviço aplique o
+// modo de falha da política.
+type CircuitBreakerStorage struct {
+	primary  RateLimiterStorage
+	fallback RateLimiterStorage
+	opts     CircuitBreakerOptions
+
+	mu             sync.Mutex
+	state          CircuitState
+	failures       int
+	openedAt       time.Time
+	probesInFlight int
+	probeSuccesses int
+}
+
+func NewCircuitBreakerStorage(primary, fallback RateLimiterStorage, opts CircuitBreakerOptions) *CircuitBreakerStorage {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 10 * time.Second
+	}
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = 1
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	return &CircuitBreakerStorage{primary: primary, fallback: fallback, opts: opts}
+}
+
+func (cb *CircuitBreakerStorage) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+// acquire indica se a próxima chamada deve ir ao primário e se ela é um probe do half-open.
+func (cb *CircuitBreakerStorage) acquire() (usePrimary, probe bool) {
+	cb.mu.Lock()
+	var transition func()
+	defer func() {
+		cb.mu.Unlock()
+		if transition != nil {
+			transition()
+		}
+	}()
+
+	switch cb.state {
+	case CircuitOpen:
+		if cb.opts.Clock.Now().Sub(cb.openedAt) < cb.opts.OpenTimeout {
+			return false, false
+		}
+		transition = cb.setState(CircuitHalfOpen)
+		cb.probeSuccesses = 0
+		cb.probesInFlight = 0
+		fallthrough
+	case CircuitHalfOpen:
+		if cb.probesInFlight >= cb.opts.HalfOpenProbes {
+			return false, false
+		}
+		cb.probesInFlight++
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+func (cb *CircuitBreakerStorage) record(err error, elapsed time.Duration, probe bool) {
+	// Cancelamento pelo cliente não diz nada sobre a saúde do primário
+	failed := (err != nil && !errors.Is(err, context.Canceled)) ||
+		(cb.opts.LatencyThreshold > 0 && elapsed > cb.opts.LatencyThreshold)
+
+	cb.mu.Lock()
+	var transition func()
+	defer func() {
+		cb.mu.Unlock()
+		if transition != nil {
+			transition()
+		}
+	}()
+
+	if probe {
+		cb.probesInFlight--
+		if cb.state != CircuitHalfOpen {
+			return
+		}
+		if failed {
+			transition = cb.open()
+			return
+		}
+		cb.probeSuccesses++
+		if cb.probeSuccesses >= cb.opts.HalfOpenProbes {
+			cb.failures = 0
+			transition = cb.setState(CircuitClosed)
+		}
+		return
+	}
+
+	if cb.state != CircuitClosed {
+		return
+	}
+	if !failed {
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.failures >= cb.opts.FailureThreshold {
+		transition = cb.open()
+	}
+}
+
+func (cb *CircuitBreakerStorage) open() func() {
+	cb.openedAt = cb.opts.Clock.Now()
+	cb.failures = 0
+	return cb.setState(CircuitOpen)
+}
+
+// setState deve ser chamado com o lock; a notificação devolvida roda depois do unlock.
+func (cb *CircuitBreakerStorage) setState(state CircuitState) func() {
+	from := cb.state
+	cb.state = state
+	if cb.opts.OnStateChange == nil || from == state {
+		return nil
+	}
+	return func() { cb.opts.OnStateChange(from, state) }
+}
+
+func callWithBreaker[T any](cb *CircuitBreakerStorage, call func(RateLimiterStorage) (T, error)) (T, error) {
+	usePrimary, probe := cb.acquire()
+	if !usePrimary {
+		return call(cb.fallback)
+	}
+
+	start := cb.opts.Clock.Now()
+	value, err := call(cb.primary)
+	cb.record(err, cb.opts.Clock.Now().Sub(start), probe)
+	return value, err
+}
+
+func execWithBreaker(cb *CircuitBreakerStorage, call func(RateLimiterStorage) error) error {
+	_, err := callWithBreaker(cb, func(s RateLimiterStorage) (struct{}, error) {
+		return struct{}{}, call(s)
+	})
+	return err
+}
+
+func (cb *CircuitBreakerStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) (int, error) { return s.IncrementRequest(ctx, key) })
+}
+
+func (cb *CircuitBreakerStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) (int, error) { return s.IncrementRequestBy(ctx, key, delta) })
+}
+
+func (cb *CircuitBreakerStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) (int, error) { return s.GetRequestCount(ctx, key) })
+}
+
+func (cb *CircuitBreakerStorage) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	return execWithBreaker(cb, func(s RateLimiterStorage) error { return s.BlockKey(ctx, key, duration) })
+}
+
+func (cb *CircuitBreakerStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) (bool, error) { return s.IsBlocked(ctx, key) })
+}
+
+func (cb *CircuitBreakerStorage) ResetKey(ctx context.Context, key string) error {
+	return execWithBreaker(cb, func(s RateLimiterStorage) error { return s.ResetKey(ctx, key) })
+}
+
+func (cb *CircuitBreakerStorage) GetBlockDuration(ctx context.Context, key string) (time.Duration, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) (time.Duration, error) { return s.GetBlockDuration(ctx, key) })
+}
+
+func (cb *CircuitBreakerStorage) GetBlockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) (time.Duration, error) { return s.GetBlockRemaining(ctx, key) })
+}
+
+func (cb *CircuitBreakerStorage) SetBlockDuration(ctx context.Context, key string, duration time.Duration) error {
+	return execWithBreaker(cb, func(s RateLimiterStorage) error { return s.SetBlockDuration(ctx, key, duration) })
+}
+
+func (cb *CircuitBreakerStorage) IncrementStrikes(ctx context.Context, key string, decay time.Duration) (int, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) (int, error) { return s.IncrementStrikes(ctx, key, decay) })
+}
+
+func (cb *CircuitBreakerStorage) UnblockKey(ctx context.Context, key string) error {
+	return execWithBreaker(cb, func(s RateLimiterStorage) error { return s.UnblockKey(ctx, key) })
+}
+
+func (cb *CircuitBreakerStorage) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) ([]string, error) { return s.ListBlockedKeys(ctx) })
+}
+
+func (cb *CircuitBreakerStorage) InspectKey(ctx context.Context, key string) (KeyInfo, error) {
+	return callWithBreaker(cb, func(s RateLimiterStorage) (KeyInfo, error) { return s.InspectKey(ctx, key) })
+}
+
+// Ping sempre consulta o primário para que os health checks reflitam o estado real do backend.
+func (cb *CircuitBreakerStorage) Ping(ctx context.Context) error {
+	return cb.primary.Ping(ctx)
+}
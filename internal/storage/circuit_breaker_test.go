@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyStorage permite ligar e desligar falhas e lentidão do storage primário. A
+// lentidão avança o relógio do teste em vez de esperar de verdade.
+type flakyStorage struct {
+	*MemoryRateLimiterStorage
+	clock *manualClock
+	mu    sync.Mutex
+	err   error
+	delay time.Duration
+	calls int
+}
+
+func newFlakyStorage(clock *manualClock) *flakyStorage {
+	return &flakyStorage{MemoryRateLimiterStorage: NewMemoryStorageWithOptions(MemoryOptions{Clock: clock}), clock: clock}
+}
+
+func (f *flakyStorage) set(err error, delay time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err, f.delay = err, delay
+}
+
+func (f *flakyStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	f.mu.Lock()
+	err, delay := f.err, f.delay
+	f.calls++
+	f.mu.Unlock()
+
+	f.clock.Advance(delay)
+	if err != nil {
+		return 0, err
+	}
+	return f.MemoryRateLimiterStorage.IncrementRequest(ctx, key)
+}
+
+func (f *flakyStorage) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestCircuitBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	primary := newFlakyStorage(clock)
+	fallback := NewMemoryStorage()
+	var transitions []string
+	cb := NewCircuitBreakerStorage(primary, fallback, CircuitBreakerOptions{
+		FailureThreshold: 3,
+		OpenTimeout:      time.Hour,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+		Clock: clock,
+	})
+
+	primary.set(errors.New("timeout"), 0)
+	for i := 0; i < 3; i++ {
+		_, err := cb.IncrementRequest(ctx, "192.168.1.1")
+		assert.Error(t, err, "As falhas abaixo do limite devem ser propagadas")
+	}
+	assert.Equal(t, CircuitOpen, cb.State())
+	assert.Equal(t, []string{"closed->open"}, transitions)
+
+	// Com o circuito aberto, o primário não é mais consultado
+	count, err := cb.IncrementRequest(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 3, primary.callCount())
+
+	fallbackCount, _ := fallback.GetRequestCount(ctx, "192.168.1.1")
+	assert.Equal(t, 1, fallbackCount)
+}
+
+func TestCircuitBreaker_SuccessResetsFailureCount(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	primary := newFlakyStorage(clock)
+	cb := NewCircuitBreakerStorage(primary, NewMemoryStorage(), CircuitBreakerOptions{FailureThreshold: 2, Clock: clock})
+
+	primary.set(errors.New("timeout"), 0)
+	cb.IncrementRequest(ctx, "key")
+	primary.set(nil, 0)
+	cb.IncrementRequest(ctx, "key")
+	primary.set(errors.New("timeout"), 0)
+	cb.IncrementRequest(ctx, "key")
+
+	assert.Equal(t, CircuitClosed, cb.State())
+}
+
+func TestCircuitBreaker_LatencyBreachCountsAsFailure(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	primary := newFlakyStorage(clock)
+	cb := NewCircuitBreakerStorage(primary, NewMemoryStorage(), CircuitBreakerOptions{
+		FailureThreshold: 2,
+		LatencyThreshold: 5 * time.Millisecond,
+		OpenTimeout:      time.Hour,
+		Clock:            clock,
+	})
+
+	primary.set(nil, 20*time.Millisecond)
+	for i := 0; i < 2; i++ {
+		_, err := cb.IncrementRequest(ctx, "key")
+		assert.NoError(t, err, "Chamadas lentas ainda devolvem o resultado do primário")
+	}
+	assert.Equal(t, CircuitOpen, cb.State())
+}
+
+func TestCircuitBreaker_HalfOpenProbesRestorePrimary(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	primary := newFlakyStorage(clock)
+	cb := NewCircuitBreakerStorage(primary, NewMemoryStorage(), CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      20 * time.Millisecond,
+		HalfOpenProbes:   2,
+		Clock:            clock,
+	})
+
+	primary.set(errors.New("timeout"), 0)
+	cb.IncrementRequest(ctx, "key")
+	assert.Equal(t, CircuitOpen, cb.State())
+
+	clock.Advance(30 * time.Millisecond)
+	primary.set(nil, 0)
+
+	cb.IncrementRequest(ctx, "key")
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+	cb.IncrementRequest(ctx, "key")
+	assert.Equal(t, CircuitClosed, cb.State())
+	assert.Equal(t, 3, primary.callCount())
+}
+
+func TestCircuitBreaker_FailedProbeReopens(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	primary := newFlakyStorage(clock)
+	cb := NewCircuitBreakerStorage(primary, NewMemoryStorage(), CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      20 * time.Millisecond,
+		HalfOpenProbes:   1,
+		Clock:            clock,
+	})
+
+	primary.set(errors.New("timeout"), 0)
+	cb.IncrementRequest(ctx, "key")
+	clock.Advance(30 * time.Millisecond)
+
+	_, err := cb.IncrementRequest(ctx, "key")
+	assert.Error(t, err)
+	assert.Equal(t, CircuitOpen, cb.State())
+}
+
+func TestCircuitBreaker_IgnoresCallerCancellation(t *testing.T) {
+	clock := newManualClock()
+	primary := newFlakyStorage(clock)
+	cb := NewCircuitBreakerStorage(primary, NewMemoryStorage(), CircuitBreakerOptions{FailureThreshold: 1, Clock: clock})
+
+	primary.set(context.Canceled, 0)
+	cb.IncrementRequest(context.Background(), "key")
+
+	assert.Equal(t, CircuitClosed, cb.State())
+}
@@ -0,0 +1,28 @@
+package storage
+
+import "time"
+
+// Clock fornece a hora atual aos backends que controlam expiração no processo,
+// para que os testes avancem o tempo sem dormir.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock é o relógio real, usado quando nenhum outro é informado.
+var SystemClock Clock = systemClock{}
+
+func clockOrSystem(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+	return clock
+}
+
+// ClockFunc adapta uma função ao Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time { return f() }
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// manualClock é o relógio manual dos testes internos do pacote, os mesmos moldes de
+// storagetest.Clock (que importa storage e por isso não pode ser usado aqui).
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,115 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"rate-limiter/internal/storage"
+	"rate-limiter/internal/storage/storagetest"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// clockHarness monta o harness dos backends que aceitam um relógio injetado.
+func clockHarness(newStorage func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage) func(t *testing.T) storagetest.Harness {
+	return func(t *testing.T) storagetest.Harness {
+		clock := storagetest.NewClock()
+		return storagetest.Harness{Storage: newStorage(t, clock), Advance: clock.Advance}
+	}
+}
+
+func TestConformance_Memory(t *testing.T) {
+	storagetest.Run(t, clockHarness(func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage {
+		return storage.NewMemoryStorageWithOptions(storage.MemoryOptions{Clock: clock})
+	}))
+}
+
+func TestConformance_MemoryBounded(t *testing.T) {
+	storagetest.Run(t, clockHarness(func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage {
+		return storage.NewMemoryStorageWithOptions(storage.MemoryOptions{Shards: 4, MaxEntries: 1000, Clock: clock})
+	}))
+}
+
+func TestConformance_Bolt(t *testing.T) {
+	storagetest.Run(t, clockHarness(func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage {
+		boltStorage, err := storage.NewBoltStorageWithOptions(filepath.Join(t.TempDir(), "rate-limiter.db"), storage.BoltOptions{Clock: clock})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { boltStorage.Close() })
+		return boltStorage
+	}))
+}
+
+func TestConformance_Gossip(t *testing.T) {
+	storagetest.Run(t, clockHarness(func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage {
+		return storage.NewGossipStorage(storage.GossipOptions{NodeID: "a", Clock: clock})
+	}))
+}
+
+// O miniredis implementa os comandos e scripts usados pelo storage e controla o TTL
+// pelo FastForward, sem precisar de Docker.
+func TestConformance_Redis(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Harness {
+		server := miniredis.RunT(t)
+		return storagetest.Harness{
+			Storage: storage.NewRedisStorage(server.Addr(), "", 0),
+			Advance: server.FastForward,
+		}
+	})
+}
+
+func TestConformance_Decorators(t *testing.T) {
+	memory := func(clock storage.Clock) storage.RateLimiterStorage {
+		return storage.NewMemoryStorageWithOptions(storage.MemoryOptions{Clock: clock})
+	}
+
+	t.Run("BlockCache", func(t *testing.T) {
+		storagetest.Run(t, clockHarness(func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage {
+			return storage.NewBlockCacheStorage(memory(clock), storage.BlockCacheOptions{Clock: clock})
+		}))
+	})
+	t.Run("BatchedCounter", func(t *testing.T) {
+		storagetest.Run(t, clockHarness(func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage {
+			return storage.NewBatchedCounterStorage(memory(clock), storage.BatchedCounterOptions{FlushInterval: time.Hour})
+		}))
+	})
+	t.Run("CircuitBreaker", func(t *testing.T) {
+		storagetest.Run(t, clockHarness(func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage {
+			return storage.NewCircuitBreakerStorage(memory(clock), memory(clock), storage.CircuitBreakerOptions{})
+		}))
+	})
+	t.Run("Sharded", func(t *testing.T) {
+		storagetest.Run(t, clockHarness(func(t *testing.T, clock storage.Clock) storage.RateLimiterStorage {
+			return storage.NewShardedStorage([]storage.ShardNode{
+				{Name: "a", Storage: memory(clock)},
+				{Name: "b", Storage: memory(clock)},
+				{Name: "c", Storage: memory(clock)},
+			}, storage.ShardedOptions{})
+		}))
+	})
+}
+
+// O Postgres usa o relógio do banco; o harness avança o tempo recuando as expirações.
+// Exige Docker, como os demais testes de integração. Um container atende todos os casos.
+func TestPostgres_Conformance(t *testing.T) {
+	ctx := context.Background()
+	postgresStorage, cleanup := storage.SetupPostgresContainer(t)
+	defer cleanup()
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Harness {
+		if err := postgresStorage.Truncate(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return storagetest.Harness{
+			Storage: postgresStorage,
+			Advance: func(d time.Duration) {
+				if err := postgresStorage.Rewind(ctx, d); err != nil {
+					t.Fatal(err)
+				}
+			},
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Detalhes internos expostos apenas aos testes do pacote storage_test.
+
+var SetupPostgresContainer = setupPostgresContainer
+
+// Rewind recua todas as expirações em d, o mesmo que o relógio do banco andar d.
+func (p *PostgresRateLimiterStorage) Rewind(ctx context.Context, d time.Duration) error {
+	interval := d.Milliseconds()
+	_, err := p.pool.Exec(ctx, `
+UPDATE rate_limiter_counters SET
+	window_start = window_start - $1::bigint * interval '1 millisecond',
+	expires_at   = expires_at - $1::bigint * interval '1 millisecond'`, interval)
+	if err != nil {
+		return err
+	}
+	for _, table := range []string{"rate_limiter_blocks", "rate_limiter_strikes"} {
+		_, err = p.pool.Exec(ctx, `UPDATE `+table+` SET expires_at = expires_at - $1::bigint * interval '1 millisecond'`, interval)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Truncate apaga contadores e bloqueios, para reaproveitar o mesmo banco entre casos.
+func (p *PostgresRateLimiterStorage) Truncate(ctx context.Context) error {
+	_, err := p.pool.Exec(ctx, `TRUNCATE rate_limiter_counters, rate_limiter_blocks, rate_limiter_strikes`)
+	return err
+}
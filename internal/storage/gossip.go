@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"rate-limiter/config"
+)
+
+const gossipPath = "/gossip"
+
+// maxGossipBodyBytes limita o corpo aceito de um par; o envio divide os slots em
+// mensagens de até metade disso.
+const maxGossipBodyBytes = 4 << 20
+
+// gossipEntryOverhead estima os bytes de uma entrada no JSON além da chave e do nó.
+const gossipEntryOverhead = 64
+
+type GossipOptions struct {
+	// NodeID identifica o slot desta instância nos G-counters; precisa ser único no grupo.
+	NodeID string
+	// Peers são as URLs base dos outros nós (ex.: http://10.0.0.2:7946).
+	Peers    []string
+	Interval time.Duration
+	// Token, se definido, é exigido dos pares como "Authorization: Bearer <token>".
+	// Sem token, ListenAndServe só aceita endereços de loopback.
+	Token  string
+	Client *http.Client
+	// Clock substitui o relógio real; usado pelos testes.
+	Clock Clock
+}
+
+type gossipWindowKey struct {
+	key    string
+	window int64
+}
+
+type gossipSlot struct {
+	count   int
+	version uint64
+}
+
+// GossipEntry é o valor de um nó para uma chave em uma janela.
+type GossipEntry struct {
+	Key    string `json:"key"`
+	Window int64  `json:"window"`
+	Node   string `json:"node"`
+	Count  int    `json:"count"`
+}
+
+type gossipMessage struct {
+	From    string        `json:"from"`
+	Entries []GossipEntry `json:"entries"`
+}
+
+// GossipStorage compartilha contagens entre instâncias sem storage central. Cada chave
+// tem um G-counter por janela fixa (um slot por nó, que só cresce); a contagem é a soma
+// dos slots e a junção de estados é o máximo de cada slot, então reenvios e entregas
+// fora de ordem são inofensivos. Cada nó envia aos pares apenas os slots alterados desde
+// o último envio bem-sucedido para aquele par.
+//
+// Bloqueios ficam no MemoryRateLimiterStorage local: como as contagens convergem, cada
+// nó bloqueia a chave por conta própria ao ver o limite global estourado. Desbloqueios e
+// ResetKey também são locais; a contagem de uma chave resetada pode voltar com a
+// próxima mensagem de um par, até a janela acabar.
+type GossipStorage struct {
+	*MemoryRateLimiterStorage
+	opts GossipOptions
+
+	mu       sync.Mutex
+	counters map[gossipWindowKey]map[string]gossipSlot
+	version  uint64
+	sent     map[string]uint64
+}
+
+func NewGossipStorage(opts GossipOptions) *GossipStorage {
+	if opts.Interval <= 0 {
+		opts.Interval = 200 * time.Millisecond
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 2 * time.Second}
+	}
+	opts.Clock = clockOrSystem(opts.Clock)
+	return &GossipStorage{
+		MemoryRateLimiterStorage: NewMemoryStorageWithOptions(MemoryOptions{Clock: opts.Clock}),
+		opts:                     opts,
+		counters:                 make(map[gossipWindowKey]map[string]gossipSlot),
+		sent:                     make(map[string]uint64),
+	}
+}
+
+func (g *GossipStorage) currentWindow() (int64, time.Duration) {
+	now := g.opts.Clock.Now()
+	start := now.Truncate(counterWindow)
+	return start.Unix(), start.Add(counterWindow).Sub(now)
+}
+
+func (g *GossipStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return g.IncrementRequestBy(ctx, key, 1)
+}
+
+func (g *GossipStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	window, _ := g.currentWindow()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	windowKey := gossipWindowKey{key: key, window: window}
+	slots, exists := g.counters[windowKey]
+	if !exists {
+		slots = make(map[string]gossipSlot)
+		g.counters[windowKey] = slots
+	}
+	g.version++
+	slots[g.opts.NodeID] = gossipSlot{count: slots[g.opts.NodeID].count + delta, version: g.version}
+	return sumSlots(slots), nil
+}
+
+func (g *GossipStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	window, _ := g.currentWindow()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return sumSlots(g.counters[gossipWindowKey{key: key, window: window}]), nil
+}
+
+func (g *GossipStorage) ResetKey(ctx context.Context, key string) error {
+	window, _ := g.currentWindow()
+
+	g.mu.Lock()
+	delete(g.counters, gossipWindowKey{key: key, window: window})
+	g.mu.Unlock()
+	return nil
+}
+
+func (g *GossipStorage) InspectKey(ctx context.Context, key string) (KeyInfo, error) {
+	info, err := g.MemoryRateLimiterStorage.InspectKey(ctx, key)
+	if err != nil {
+		return info, err
+	}
+
+	window, remaining := g.currentWindow()
+	g.mu.Lock()
+	slots, exists := g.counters[gossipWindowKey{key: key, window: window}]
+	info.Count = sumSlots(slots)
+	g.mu.Unlock()
+	if exists {
+		info.CountTTL = remaining
+	}
+	return info, nil
+}
+
+func sumSlots(slots map[string]gossipSlot) int {
+	total := 0
+	for _, slot := range slots {
+		total += slot.count
+	}
+	return total
+}
+
+// Merge junta entradas recebidas de um par, ficando com o maior valor de cada slot.
+// Só a janela atual e a seguinte (tolerância a relógios levemente adiantados) são
+// aceitas: janelas encerradas não influenciam mais nenhuma decisão, e janelas
+// futuras nunca expirariam a tempo.
+func (g *GossipStorage) Merge(entries []GossipEntry) {
+	window, _ := g.currentWindow()
+	next := window + int64(counterWindow/time.Second)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.Window != window && entry.Window != next {
+			continue
+		}
+		windowKey := gossipWindowKey{key: entry.Key, window: entry.Window}
+		slots, exists := g.counters[windowKey]
+		if !exists {
+			slots = make(map[string]gossipSlot)
+			g.counters[windowKey] = slots
+		}
+		if entry.Count > slots[entry.Node].count {
+			// A nova versão faz o valor seguir adiante para os pares que ainda não o viram
+			g.version++
+			slots[entry.Node] = gossipSlot{count: entry.Count, version: g.version}
+		}
+	}
+}
+
+// Gossip envia a cada par os slots alterados desde o último envio aceito por ele e
+// descarta as janelas encerradas.
+func (g *GossipStorage) Gossip(ctx context.Context) error {
+	g.expireWindows()
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, peer := range g.opts.Peers {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			if err := g.pushTo(ctx, peer); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("gossip para %s: %w", peer, err))
+				mu.Unlock()
+			}
+		}(peer)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Run executa Gossip a cada Interval até o contexto ser cancelado.
+func (g *GossipStorage) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(g.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := g.Gossip(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (g *GossipStorage) pushTo(ctx context.Context, peer string) error {
+	entries, version := g.changedSince(peer)
+
+	// Divide em mensagens que caibam no limite do receptor
+	for len(entries) > 0 {
+		size, n := 0, 0
+		for n < len(entries) && (n == 0 || size < maxGossipBodyBytes/2) {
+			size += len(entries[n].Key) + len(entries[n].Node) + gossipEntryOverhead
+			n++
+		}
+		if err := g.send(ctx, peer, entries[:n]); err != nil {
+			return err
+		}
+		entries = entries[n:]
+	}
+
+	// Só avança depois do aceite de todas as mensagens: se um envio falhar, os mesmos
+	// slots vão na próxima rodada
+	g.mu.Lock()
+	if version > g.sent[peer] {
+		g.sent[peer] = version
+	}
+	g.mu.Unlock()
+	return nil
+}
+
+func (g *GossipStorage) send(ctx context.Context, peer string, entries []GossipEntry) error {
+	body, err := json.Marshal(gossipMessage{From: g.opts.NodeID, Entries: entries})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(peer, "/")+gossipPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.opts.Token)
+	}
+
+	resp, err := g.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (g *GossipStorage) changedSince(peer string) ([]GossipEntry, uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	since := g.sent[peer]
+	var entries []GossipEntry
+	for windowKey, slots := range g.counters {
+		for node, slot := range slots {
+			if slot.version > since {
+				entries = append(entries, GossipEntry{Key: windowKey.key, Window: windowKey.window, Node: node, Count: slot.count})
+			}
+		}
+	}
+	return entries, g.version
+}
+
+func (g *GossipStorage) expireWindows() {
+	window, _ := g.currentWindow()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for windowKey := range g.counters {
+		if windowKey.window < window {
+			delete(g.counters, windowKey)
+		}
+	}
+}
+
+// Handler recebe as mensagens dos pares em POST /gossip.
+func (g *GossipStorage) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(gossipPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if g.opts.Token != "" {
+			provided, hasScheme := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !hasScheme || subtle.ConstantTimeCompare([]byte(provided), []byte(g.opts.Token)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		var msg gossipMessage
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGossipBodyBytes)).Decode(&msg); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		g.Merge(msg.Entries)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// ListenAndServe recebe as mensagens dos pares em addr. Sem Token, qualquer um que
+// alcance a porta poderia inflar contadores e bloquear IPs e tokens alheios, então
+// nesse caso só endereços de loopback são aceitos.
+func (g *GossipStorage) ListenAndServe(addr string) error {
+	if g.opts.Token == "" && !config.IsLoopbackAddr(addr) {
+		return fmt.Errorf("gossip sem token só pode escutar em loopback (addr %q)", addr)
+	}
+	return http.ListenAndServe(addr, g.Handler())
+}
@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startGossipCluster sobe n nós em processo, cada um com seu servidor HTTP em localhost
+func startGossipCluster(t *testing.T, n int, token string) []*GossipStorage {
+	servers := make([]*httptest.Server, n)
+	urls := make([]string, n)
+	for i := range servers {
+		servers[i] = httptest.NewUnstartedServer(nil)
+		urls[i] = "http://" + servers[i].Listener.Addr().String()
+	}
+
+	// Relógio fixo para uma virada de minuto não zerar as contagens no meio do teste
+	clock := ClockFunc(func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) })
+	nodes := make([]*GossipStorage, n)
+	for i := range nodes {
+		var peers []string
+		for j, url := range urls {
+			if j != i {
+				peers = append(peers, url)
+			}
+		}
+		nodes[i] = NewGossipStorage(GossipOptions{NodeID: urls[i], Peers: peers, Token: token, Clock: clock})
+		servers[i].Config.Handler = nodes[i].Handler()
+		servers[i].Start()
+		t.Cleanup(servers[i].Close)
+	}
+	return nodes
+}
+
+func gossipRound(t *testing.T, nodes []*GossipStorage) {
+	for _, node := range nodes {
+		assert.NoError(t, node.Gossip(context.Background()))
+	}
+}
+
+func TestGossip_NodesConvergeToGlobalCount(t *testing.T) {
+	ctx := context.Background()
+	nodes := startGossipCluster(t, 3, "")
+
+	for i, node := range nodes {
+		for j := 0; j <= i; j++ {
+			_, err := node.IncrementRequest(ctx, "ip:1")
+			assert.NoError(t, err)
+		}
+	}
+
+	gossipRound(t, nodes)
+
+	for _, node := range nodes {
+		count, _ := node.GetRequestCount(ctx, "ip:1")
+		assert.Equal(t, 6, count)
+	}
+
+	// O próximo incremento em qualquer nó já enxerga o total global
+	count, _ := nodes[0].IncrementRequest(ctx, "ip:1")
+	assert.Equal(t, 7, count)
+}
+
+func TestGossip_GlobalLimitAcrossNodes(t *testing.T) {
+	ctx := context.Background()
+	nodes := startGossipCluster(t, 3, "")
+	const limit = 30
+
+	allowed := 0
+	for i := 0; i < 90; i++ {
+		count, _ := nodes[i%3].IncrementRequest(ctx, "ip:1")
+		if count <= limit {
+			allowed++
+		}
+		// Uma rodada de gossip a cada 9 requisições
+		if i%9 == 8 {
+			gossipRound(t, nodes)
+		}
+	}
+
+	// Entre rodadas cada nó só enxerga as próprias requisições novas
+	assert.GreaterOrEqual(t, allowed, limit)
+	assert.LessOrEqual(t, allowed, limit+2*9)
+}
+
+func TestGossip_MergeIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	node := NewGossipStorage(GossipOptions{NodeID: "a"})
+	window, _ := node.currentWindow()
+
+	entries := []GossipEntry{{Key: "ip:1", Window: window, Node: "b", Count: 4}}
+	node.Merge(entries)
+	node.Merge(entries)
+	// Mensagem atrasada com valor antigo não faz o contador voltar
+	node.Merge([]GossipEntry{{Key: "ip:1", Window: window, Node: "b", Count: 2}})
+
+	count, _ := node.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 4, count)
+}
+
+func TestGossip_SendsOnlyChangedSlots(t *testing.T) {
+	ctx := context.Background()
+	var received atomic.Int64
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg gossipMessage
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		received.Add(int64(len(msg.Entries)))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer peer.Close()
+
+	node := NewGossipStorage(GossipOptions{NodeID: "a", Peers: []string{peer.URL}})
+	_, _ = node.IncrementRequest(ctx, "ip:1")
+	_, _ = node.IncrementRequest(ctx, "ip:2")
+
+	assert.NoError(t, node.Gossip(ctx))
+	assert.Equal(t, int64(2), received.Load())
+
+	// Sem mudanças, nada é enviado
+	assert.NoError(t, node.Gossip(ctx))
+	assert.Equal(t, int64(2), received.Load())
+
+	_, _ = node.IncrementRequest(ctx, "ip:1")
+	assert.NoError(t, node.Gossip(ctx))
+	assert.Equal(t, int64(3), received.Load())
+}
+
+func TestGossip_RetriesAfterPeerFailure(t *testing.T) {
+	ctx := context.Background()
+	var failing atomic.Bool
+	failing.Store(true)
+	receiver := NewGossipStorage(GossipOptions{NodeID: "b"})
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		receiver.Handler().ServeHTTP(w, r)
+	}))
+	defer peer.Close()
+
+	node := NewGossipStorage(GossipOptions{NodeID: "a", Peers: []string{peer.URL}})
+	_, _ = node.IncrementRequestBy(ctx, "ip:1", 3)
+
+	assert.Error(t, node.Gossip(ctx))
+
+	failing.Store(false)
+	assert.NoError(t, node.Gossip(ctx))
+	count, _ := receiver.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 3, count)
+}
+
+func TestGossip_RequiresToken(t *testing.T) {
+	ctx := context.Background()
+	nodes := startGossipCluster(t, 2, "segredo")
+	_, _ = nodes[0].IncrementRequest(ctx, "ip:1")
+	gossipRound(t, nodes)
+
+	count, _ := nodes[1].GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 1, count)
+
+	intruder := NewGossipStorage(GossipOptions{NodeID: "x", Peers: nodes[0].opts.Peers})
+	_, _ = intruder.IncrementRequest(ctx, "ip:1")
+	assert.ErrorContains(t, intruder.Gossip(ctx), "401")
+}
+
+func TestGossip_MergeAcceptsOnlyCurrentAndNextWindow(t *testing.T) {
+	node := NewGossipStorage(GossipOptions{NodeID: "a"})
+	window, _ := node.currentWindow()
+	minute := int64(time.Minute / time.Second)
+
+	node.Merge([]GossipEntry{
+		{Key: "ip:1", Window: window, Node: "b", Count: 1},
+		{Key: "ip:1", Window: window + minute, Node: "b", Count: 2},
+		// Relógio muito adiantado ou mensagem forjada: nunca expiraria
+		{Key: "ip:1", Window: window + 10*minute, Node: "b", Count: 3},
+		{Key: "ip:1", Window: 1 << 40, Node: "b", Count: 4},
+		{Key: "ip:1", Window: window - minute, Node: "b", Count: 5},
+	})
+
+	assert.Len(t, node.counters, 2)
+}
+
+func TestGossip_HandlerLimits(t *testing.T) {
+	node := NewGossipStorage(GossipOptions{NodeID: "a", Token: "segredo"})
+
+	post := func(authorization, body string) int {
+		req := httptest.NewRequest(http.MethodPost, gossipPath, strings.NewReader(body))
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		node.Handler().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// O token sem o esquema Bearer não é aceito
+	assert.Equal(t, http.StatusUnauthorized, post("segredo", `{"entries":[]}`))
+	assert.Equal(t, http.StatusNoContent, post("Bearer segredo", `{"entries":[]}`))
+
+	huge := `{"entries":[{"key":"` + strings.Repeat("a", maxGossipBodyBytes) + `"}]}`
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("Bearer segredo", huge))
+}
+
+func TestGossip_SplitsLargeUpdates(t *testing.T) {
+	ctx := context.Background()
+	var messages atomic.Int64
+	receiver := NewGossipStorage(GossipOptions{NodeID: "b"})
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		messages.Add(1)
+		receiver.Handler().ServeHTTP(w, r)
+	}))
+	defer peer.Close()
+
+	node := NewGossipStorage(GossipOptions{NodeID: "a", Peers: []string{peer.URL}})
+	longKey := strings.Repeat("k", 1<<20)
+	for i := 0; i < 5; i++ {
+		_, _ = node.IncrementRequest(ctx, fmt.Sprintf("%s:%d", longKey, i))
+	}
+
+	assert.NoError(t, node.Gossip(ctx))
+	assert.Greater(t, messages.Load(), int64(1))
+	count, _ := receiver.GetRequestCount(ctx, longKey+":4")
+	assert.Equal(t, 1, count)
+}
+
+func TestGossip_ListenRequiresTokenOutsideLoopback(t *testing.T) {
+	node := NewGossipStorage(GossipOptions{NodeID: "a"})
+	assert.ErrorContains(t, node.ListenAndServe(":7946"), "loopback")
+	assert.ErrorContains(t, node.ListenAndServe("0.0.0.0:7946"), "loopback")
+}
+
+func TestGossip_WindowRollover(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC)
+	node := NewGossipStorage(GossipOptions{NodeID: "a", Clock: ClockFunc(func() time.Time { return now })})
+
+	_, _ = node.IncrementRequestBy(ctx, "ip:1", 5)
+	info, _ := node.InspectKey(ctx, "ip:1")
+	assert.Equal(t, 5, info.Count)
+	assert.Equal(t, 30*time.Second, info.CountTTL)
+
+	now = now.Add(time.Minute)
+	count, _ := node.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 0, count)
+
+	assert.NoError(t, node.Gossip(ctx))
+	assert.Empty(t, node.counters, "Janelas encerradas devem ser descartadas")
+}
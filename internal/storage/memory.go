@@ -1,92 +1,343 @@
 package storage
 
 import (
+	"container/heap"
+	"container/list"
+	"context"
+	"hash/maphash"
+	"sort"
 	"sync"
 	"time"
 )
 
+const defaultMemoryShards = 64
+
+// MemoryRateLimiterStorage distribui as chaves em shards pelo hash, cada um com seu
+// próprio lock, para que requisições de chaves diferentes não disputem o mesmo mutex.
 type MemoryRateLimiterStorage struct {
+	seed   maphash.Seed
+	shards []*memoryShard
+	clock  Clock
+}
+
+type memoryShard struct {
 	mu             sync.Mutex
-	requests       map[string]int
+	requests       map[string]memoryCounter
 	blocked        map[string]time.Time
 	blockDurations map[string]time.Duration
+	strikes        map[string]memoryCounter
+	expiries       expiryHeap
+	scheduled      map[expiryID]*expiryEntry
+
+	// Controle de memória, usado apenas quando há limite (limits != nil)
+	limits  *memoryLimits
+	entries map[string]*list.Element
+	idle    *list.List
+	active  *list.List
+	bytes   int
+}
+
+// memoryCounter segue a mesma janela fixa dos demais backends: começa no primeiro
+// incremento e some quando vence.
+type memoryCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+type MemoryOptions struct {
+	Shards int
+	// MaxEntries e MaxBytes limitam o total de chaves guardadas; zero desliga o limite.
+	// O orçamento é dividido igualmente entre os shards.
+	MaxEntries int
+	MaxBytes   int
+	// OnEvict é chamado com o lock do shard travado a cada chave descartada.
+	OnEvict func(blocked bool)
+	// Clock substitui o relógio real; usado pelos testes.
+	Clock Clock
 }
 
 func NewMemoryStorage() *MemoryRateLimiterStorage {
-	return &MemoryRateLimiterStorage{
-		requests:       make(map[string]int),
-		blocked:        make(map[string]time.Time),
-		blockDurations: make(map[string]time.Duration),
+	return NewMemoryStorageWithOptions(MemoryOptions{})
+}
+
+func NewShardedMemoryStorage(shards int) *MemoryRateLimiterStorage {
+	return NewMemoryStorageWithOptions(MemoryOptions{Shards: shards})
+}
+
+func NewMemoryStorageWithOptions(opts MemoryOptions) *MemoryRateLimiterStorage {
+	if opts.Shards <= 0 {
+		opts.Shards = defaultMemoryShards
+	}
+	m := &MemoryRateLimiterStorage{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*memoryShard, opts.Shards),
+		clock:  clockOrSystem(opts.Clock),
 	}
+	limits := newMemoryLimits(opts)
+	for i := range m.shards {
+		m.shards[i] = &memoryShard{limits: limits}
+		m.shards[i].reset()
+	}
+	return m
 }
 
-func (m *MemoryRateLimiterStorage) IncrementRequest(key string) (int, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (s *memoryShard) reset() {
+	s.requests = make(map[string]memoryCounter)
+	s.blocked = make(map[string]time.Time)
+	s.blockDurations = make(map[string]time.Duration)
+	s.strikes = make(map[string]memoryCounter)
+	s.expiries = nil
+	s.scheduled = make(map[expiryID]*expiryEntry)
+	if s.limits != nil {
+		s.entries = make(map[string]*list.Element)
+		s.idle = list.New()
+		s.active = list.New()
+		s.bytes = 0
+	}
+}
 
-	m.requests[key]++
-	return m.requests[key], nil
+// lock trava o shard da chave, já descarta os contadores e bloqueios vencidos dele e
+// devolve o instante usado, para que a operação inteira enxergue o mesmo relógio.
+func (m *MemoryRateLimiterStorage) lock(key string) (*memoryShard, time.Time) {
+	shard := m.shards[maphash.String(m.seed, key)%uint64(len(m.shards))]
+	shard.mu.Lock()
+	now := m.clock.Now()
+	shard.purgeExpired(now)
+	return shard, now
 }
 
-func (m *MemoryRateLimiterStorage) GetRequestCount(key string) (int, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *MemoryRateLimiterStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return m.IncrementRequestBy(ctx, key, 1)
+}
 
-	count, exists := m.requests[key]
+func (m *MemoryRateLimiterStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	shard, now := m.lock(key)
+	defer shard.mu.Unlock()
+
+	counter, exists := shard.requests[key]
 	if !exists {
-		return 0, nil
+		counter.expiresAt = now.Add(counterWindow)
+		shard.schedule(key, expiryCounter, counter.expiresAt)
 	}
-	return count, nil
+	counter.count += delta
+	shard.requests[key] = counter
+	shard.track(key, true)
+	return counter.count, nil
+}
+
+func (m *MemoryRateLimiterStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	shard, _ := m.lock(key)
+	defer shard.mu.Unlock()
+
+	shard.track(key, true)
+	return shard.requests[key].count, nil
 }
 
-func (m *MemoryRateLimiterStorage) BlockKey(key string, duration time.Duration) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *MemoryRateLimiterStorage) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	shard, now := m.lock(key)
+	defer shard.mu.Unlock()
 
-	m.blocked[key] = time.Now().Add(duration)
-	m.blockDurations[key] = duration
+	expiryTime := now.Add(duration)
+	shard.blocked[key] = expiryTime
+	shard.blockDurations[key] = duration
+	shard.schedule(key, expiryBlock, expiryTime)
+	shard.track(key, true)
 	return nil
 }
 
-func (m *MemoryRateLimiterStorage) IsBlocked(key string) (bool, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *MemoryRateLimiterStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	shard, _ := m.lock(key)
+	defer shard.mu.Unlock()
 
-	expiryTime, exists := m.blocked[key]
-	if !exists {
-		return false, nil
-	}
-	if time.Now().After(expiryTime) {
-		delete(m.blocked, key)
-		delete(m.blockDurations, key)
-		return false, nil
+	_, exists := shard.blocked[key]
+	shard.track(key, true)
+	return exists, nil
+}
+
+func (m *MemoryRateLimiterStorage) ResetKey(ctx context.Context, key string) error {
+	shard, _ := m.lock(key)
+	defer shard.mu.Unlock()
+
+	delete(shard.requests, key)
+	shard.unschedule(key, expiryCounter)
+	shard.track(key, false)
+	return nil
+}
+
+func (m *MemoryRateLimiterStorage) GetBlockDuration(ctx context.Context, key string) (time.Duration, error) {
+	shard, _ := m.lock(key)
+	defer shard.mu.Unlock()
+
+	shard.track(key, true)
+	return shard.blockDurations[key], nil
+}
+
+func (m *MemoryRateLimiterStorage) GetBlockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	shard, now := m.lock(key)
+	defer shard.mu.Unlock()
+
+	shard.track(key, true)
+	if expiryTime, exists := shard.blocked[key]; exists {
+		return expiryTime.Sub(now), nil
 	}
-	return true, nil
+	return 0, nil
 }
 
-func (m *MemoryRateLimiterStorage) ResetKey(key string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// SetBlockDuration só troca a duração de um bloqueio ativo, como no Redis; sem
+// bloqueio não haveria expiração para levar a duração embora.
+func (m *MemoryRateLimiterStorage) SetBlockDuration(ctx context.Context, key string, duration time.Duration) error {
+	shard, _ := m.lock(key)
+	defer shard.mu.Unlock()
 
-	delete(m.requests, key)
+	if _, exists := shard.blocked[key]; !exists {
+		return nil
+	}
+	shard.blockDurations[key] = duration
+	shard.track(key, true)
 	return nil
 }
 
-func (m *MemoryRateLimiterStorage) GetBlockDuration(key string) (time.Duration, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// IncrementStrikes soma uma violação e adia o esquecimento para decay a partir de agora.
+func (m *MemoryRateLimiterStorage) IncrementStrikes(ctx context.Context, key string, decay time.Duration) (int, error) {
+	shard, now := m.lock(key)
+	defer shard.mu.Unlock()
 
-	duration, exists := m.blockDurations[key]
-	if !exists {
-		return 0, nil
+	strikes := shard.strikes[key]
+	strikes.count++
+	strikes.expiresAt = now.Add(decay)
+	shard.strikes[key] = strikes
+	shard.schedule(key, expiryStrikes, strikes.expiresAt)
+	shard.track(key, true)
+	return strikes.count, nil
+}
+
+func (m *MemoryRateLimiterStorage) UnblockKey(ctx context.Context, key string) error {
+	shard, _ := m.lock(key)
+	defer shard.mu.Unlock()
+
+	delete(shard.blocked, key)
+	delete(shard.blockDurations, key)
+	shard.unschedule(key, expiryBlock)
+	shard.track(key, false)
+	return nil
+}
+
+func (m *MemoryRateLimiterStorage) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	now := m.clock.Now()
+	keys := []string{}
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		shard.purgeExpired(now)
+		for key := range shard.blocked {
+			keys = append(keys, key)
+		}
+		shard.mu.Unlock()
 	}
-	return duration, nil
+	sort.Strings(keys)
+	return keys, nil
 }
 
-func (m *MemoryRateLimiterStorage) SetBlockDuration(key string, duration time.Duration) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *MemoryRateLimiterStorage) InspectKey(ctx context.Context, key string) (KeyInfo, error) {
+	shard, now := m.lock(key)
+	defer shard.mu.Unlock()
 
-	m.blockDurations[key] = duration
+	info := KeyInfo{Key: key}
+	if counter, exists := shard.requests[key]; exists {
+		info.Count = counter.count
+		info.CountTTL = counter.expiresAt.Sub(now)
+	}
+	if expiryTime, exists := shard.blocked[key]; exists {
+		info.Blocked = true
+		info.BlockRemaining = expiryTime.Sub(now)
+	}
+	return info, nil
+}
+
+// Ping existe para satisfazer o health check; o armazenamento em memória está sempre disponível.
+func (m *MemoryRateLimiterStorage) Ping(ctx context.Context) error {
 	return nil
 }
+
+// purgeExpired remove contadores, bloqueios e violações vencidos na ordem do heap, sem varrer os mapas.
+func (s *memoryShard) purgeExpired(now time.Time) {
+	for len(s.expiries) > 0 && !s.expiries[0].expiresAt.After(now) {
+		entry := heap.Pop(&s.expiries).(*expiryEntry)
+		delete(s.scheduled, expiryID{entry.key, entry.kind})
+		switch entry.kind {
+		case expiryCounter:
+			delete(s.requests, entry.key)
+		case expiryStrikes:
+			delete(s.strikes, entry.key)
+		default:
+			delete(s.blocked, entry.key)
+			delete(s.blockDurations, entry.key)
+		}
+		s.track(entry.key, false)
+	}
+}
+
+// schedule mantém uma única entrada no heap por chave e tipo: renovar um bloqueio ou
+// uma violação move a entrada existente em vez de empilhar outra.
+func (s *memoryShard) schedule(key string, kind expiryKind, expiresAt time.Time) {
+	id := expiryID{key, kind}
+	if entry, exists := s.scheduled[id]; exists {
+		entry.expiresAt = expiresAt
+		heap.Fix(&s.expiries, entry.index)
+		return
+	}
+	entry := &expiryEntry{key: key, expiresAt: expiresAt, kind: kind}
+	heap.Push(&s.expiries, entry)
+	s.scheduled[id] = entry
+}
+
+func (s *memoryShard) unschedule(key string, kind expiryKind) {
+	id := expiryID{key, kind}
+	if entry, exists := s.scheduled[id]; exists {
+		heap.Remove(&s.expiries, entry.index)
+		delete(s.scheduled, id)
+	}
+}
+
+type expiryKind uint8
+
+const (
+	expiryBlock expiryKind = iota
+	expiryCounter
+	expiryStrikes
+)
+
+type expiryID struct {
+	key  string
+	kind expiryKind
+}
+
+type expiryEntry struct {
+	key       string
+	expiresAt time.Time
+	kind      expiryKind
+	// index é a posição no heap, usada por heap.Fix e heap.Remove.
+	index int
+}
+
+// expiryHeap é um min-heap pelo instante de expiração.
+type expiryHeap []*expiryEntry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *expiryHeap) Push(x any) {
+	entry := x.(*expiryEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+func (h *expiryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// singleLockMemoryStorage reproduz a implementação anterior (um único mutex para os
+// três mapas) apenas como referência para os benchmarks.
+type singleLockMemoryStorage struct {
+	mu       sync.Mutex
+	requests map[string]int
+	blocked  map[string]time.Time
+}
+
+func newSingleLockMemoryStorage() *singleLockMemoryStorage {
+	return &singleLockMemoryStorage{
+		requests: make(map[string]int),
+		blocked:  make(map[string]time.Time),
+	}
+}
+
+func (m *singleLockMemoryStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[key]++
+	return m.requests[key], nil
+}
+
+func (m *singleLockMemoryStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiryTime, exists := m.blocked[key]
+	if !exists {
+		return false, nil
+	}
+	if time.Now().After(expiryTime) {
+		delete(m.blocked, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+// hotPath é o par de chamadas que o serviço faz a cada requisição permitida.
+type hotPath interface {
+	IncrementRequest(ctx context.Context, key string) (int, error)
+	IsBlocked(ctx context.Context, key string) (bool, error)
+}
+
+func benchmarkHotPath(b *testing.B, newStorage func() hotPath) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("rate_limiter:ip:10.0.%d.%d", i/256, i%256)
+	}
+
+	// RunParallel sobe parallelism × GOMAXPROCS goroutines; o total sai na métrica workers
+	for _, parallelism := range []int{1, 16, 64, 256} {
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			ctx := context.Background()
+			storage := newStorage()
+			var next atomic.Uint64
+
+			b.SetParallelism(parallelism)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					key := keys[next.Add(1)%uint64(len(keys))]
+					_, _ = storage.IsBlocked(ctx, key)
+					_, _ = storage.IncrementRequest(ctx, key)
+				}
+			})
+			b.ReportMetric(float64(parallelism*runtime.GOMAXPROCS(0)), "workers")
+		})
+	}
+}
+
+func BenchmarkMemory_Sharded(b *testing.B) {
+	benchmarkHotPath(b, func() hotPath { return NewMemoryStorage() })
+}
+
+func BenchmarkMemory_SingleLock(b *testing.B) {
+	benchmarkHotPath(b, func() hotPath { return newSingleLockMemoryStorage() })
+}
@@ -0,0 +1,133 @@
+package storage
+
+import "container/list"
+
+// memoryEntryOverhead é uma estimativa do custo fixo de uma chave nos mapas e na
+// lista LRU, somado ao tamanho da chave para o orçamento em bytes.
+const memoryEntryOverhead = 160
+
+// expiryEntryOverhead estima o custo de cada entrada agendada: a entrada, a posição
+// no heap e o índice por chave. Uma chave tem no máximo uma por tipo.
+const expiryEntryOverhead = 120
+
+type memoryLimits struct {
+	maxEntries int
+	maxBytes   int
+	onEvict    func(blocked bool)
+}
+
+func newMemoryLimits(opts MemoryOptions) *memoryLimits {
+	if opts.MaxEntries <= 0 && opts.MaxBytes <= 0 {
+		return nil
+	}
+	return &memoryLimits{
+		maxEntries: perShard(opts.MaxEntries, opts.Shards),
+		maxBytes:   perShard(opts.MaxBytes, opts.Shards),
+		onEvict:    opts.OnEvict,
+	}
+}
+
+func perShard(total, shards int) int {
+	if total <= 0 {
+		return 0
+	}
+	return max((total+shards-1)/shards, 1)
+}
+
+type lruEntry struct {
+	key    string
+	size   int
+	active bool
+}
+
+// track mantém a chave na lista certa: "active" para bloqueios vigentes, "idle" para o
+// resto. A evicção sempre esvazia idle antes de tocar em um bloqueio, para que um
+// atacante não consiga se desbloquear inundando o storage com chaves novas.
+// refresh marca a chave como usada agora; chaves sem nenhum dado saem das listas.
+func (s *memoryShard) track(key string, refresh bool) {
+	if s.limits == nil {
+		return
+	}
+
+	_, hasCount := s.requests[key]
+	_, isBlocked := s.blocked[key]
+	_, hasDuration := s.blockDurations[key]
+	_, hasStrikes := s.strikes[key]
+	element, tracked := s.entries[key]
+
+	if !hasCount && !isBlocked && !hasDuration && !hasStrikes {
+		if tracked {
+			s.removeEntry(element)
+		}
+		return
+	}
+
+	// Chaves já rastreadas também crescem (nova janela, reincidência, bloqueio agendado
+	// no heap), então o orçamento é conferido a cada chamada, não só na inserção
+	defer s.evict(key)
+
+	if !tracked {
+		entry := &lruEntry{key: key, size: len(key) + memoryEntryOverhead, active: isBlocked}
+		s.entries[key] = s.listFor(isBlocked).PushFront(entry)
+		s.bytes += entry.size
+		return
+	}
+
+	entry := element.Value.(*lruEntry)
+	if entry.active != isBlocked {
+		s.listFor(entry.active).Remove(element)
+		entry.active = isBlocked
+		s.entries[key] = s.listFor(isBlocked).PushFront(entry)
+		return
+	}
+	if refresh {
+		s.listFor(isBlocked).MoveToFront(element)
+	}
+}
+
+func (s *memoryShard) listFor(active bool) *list.List {
+	if active {
+		return s.active
+	}
+	return s.idle
+}
+
+// evict descarta as chaves menos usadas até o shard voltar ao orçamento, nunca a que acabou de entrar.
+func (s *memoryShard) evict(protected string) {
+	for s.overBudget() {
+		victim := s.idle.Back()
+		if victim == nil || victim.Value.(*lruEntry).key == protected {
+			victim = s.active.Back()
+		}
+		if victim == nil || victim.Value.(*lruEntry).key == protected {
+			return
+		}
+
+		entry := victim.Value.(*lruEntry)
+		delete(s.requests, entry.key)
+		delete(s.blocked, entry.key)
+		delete(s.blockDurations, entry.key)
+		delete(s.strikes, entry.key)
+		for _, kind := range []expiryKind{expiryBlock, expiryCounter, expiryStrikes} {
+			s.unschedule(entry.key, kind)
+		}
+		s.removeEntry(victim)
+		if s.limits.onEvict != nil {
+			s.limits.onEvict(entry.active)
+		}
+	}
+}
+
+func (s *memoryShard) overBudget() bool {
+	if s.limits.maxEntries > 0 && len(s.entries) > s.limits.maxEntries {
+		return true
+	}
+	return s.limits.maxBytes > 0 && s.bytes+len(s.expiries)*expiryEntryOverhead > s.limits.maxBytes
+}
+
+func (s *memoryShard) removeEntry(element *list.Element) {
+	entry := element.Value.(*lruEntry)
+	s.listFor(entry.active).Remove(element)
+	delete(s.entries, entry.key)
+	s.bytes -= entry.size
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLimits_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	var evictions atomic.Int64
+	storage := NewMemoryStorageWithOptions(MemoryOptions{
+		Shards:     1,
+		MaxEntries: 3,
+		OnEvict:    func(bool) { evictions.Add(1) },
+	})
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, _ = storage.IncrementRequest(ctx, key)
+	}
+	// "a" volta a ser usada, então "b" passa a ser a menos recente
+	_, _ = storage.IsBlocked(ctx, "a")
+	_, _ = storage.IncrementRequest(ctx, "d")
+
+	count, _ := storage.GetRequestCount(ctx, "b")
+	assert.Equal(t, 0, count, "b deveria ter sido descartada")
+	count, _ = storage.GetRequestCount(ctx, "a")
+	assert.Equal(t, 1, count)
+	assert.Equal(t, int64(1), evictions.Load())
+}
+
+func TestMemoryLimits_FloodOfUniqueKeysStaysBounded(t *testing.T) {
+	ctx := context.Background()
+	var idleEvictions, blockedEvictions atomic.Int64
+	storage := NewMemoryStorageWithOptions(MemoryOptions{
+		Shards:     8,
+		MaxEntries: 1000,
+		OnEvict: func(blocked bool) {
+			if blocked {
+				blockedEvictions.Add(1)
+			} else {
+				idleEvictions.Add(1)
+			}
+		},
+	})
+
+	// Bloqueios legítimos criados antes do ataque
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, storage.BlockKey(ctx, fmt.Sprintf("blocked:%d", i), time.Hour))
+	}
+
+	// Atacante rotacionando IPs
+	for i := 0; i < 100000; i++ {
+		_, _ = storage.IncrementRequest(ctx, fmt.Sprintf("ip:%d", i))
+	}
+
+	total := 0
+	for _, shard := range storage.shards {
+		total += len(shard.entries)
+		assert.LessOrEqual(t, len(shard.requests), 125)
+		// As chaves descartadas também saem do heap de expiração
+		assert.LessOrEqual(t, len(shard.expiries), 125)
+		assert.Len(t, shard.scheduled, len(shard.expiries))
+	}
+	assert.LessOrEqual(t, total, 1000)
+	assert.Equal(t, int64(0), blockedEvictions.Load(), "Bloqueios ativos não podem sair antes das chaves ociosas")
+	assert.Greater(t, idleEvictions.Load(), int64(98000))
+
+	keys, _ := storage.ListBlockedKeys(ctx)
+	assert.Len(t, keys, 50)
+}
+
+func TestMemoryLimits_BlocksAreEvictedOnlyAsLastResort(t *testing.T) {
+	ctx := context.Background()
+	var blockedEvictions atomic.Int64
+	storage := NewMemoryStorageWithOptions(MemoryOptions{
+		Shards:     1,
+		MaxEntries: 2,
+		OnEvict: func(blocked bool) {
+			if blocked {
+				blockedEvictions.Add(1)
+			}
+		},
+	})
+
+	assert.NoError(t, storage.BlockKey(ctx, "old", time.Hour))
+	assert.NoError(t, storage.BlockKey(ctx, "new", time.Hour))
+	assert.NoError(t, storage.BlockKey(ctx, "newest", time.Hour))
+
+	keys, _ := storage.ListBlockedKeys(ctx)
+	assert.Equal(t, []string{"new", "newest"}, keys)
+	assert.Equal(t, int64(1), blockedEvictions.Load())
+}
+
+func TestMemoryLimits_ExpiredBlockBecomesEvictable(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	storage := NewMemoryStorageWithOptions(MemoryOptions{Shards: 1, MaxEntries: 2, Clock: clock})
+
+	_, _ = storage.IncrementRequest(ctx, "expired")
+	assert.NoError(t, storage.BlockKey(ctx, "expired", 10*time.Millisecond))
+	assert.NoError(t, storage.BlockKey(ctx, "active", time.Hour))
+	clock.Advance(20 * time.Millisecond)
+
+	// Ao vencer, o bloqueio volta para a lista ociosa e é o primeiro a sair
+	_, _ = storage.IncrementRequest(ctx, "fresh")
+
+	blocked, _ := storage.IsBlocked(ctx, "active")
+	assert.True(t, blocked)
+	count, _ := storage.GetRequestCount(ctx, "expired")
+	assert.Equal(t, 0, count)
+}
+
+func TestMemoryLimits_ByteBudget(t *testing.T) {
+	ctx := context.Background()
+	// Cada contador custa a chave, a entrada nos mapas e a entrada no heap de expiração
+	perKey := memoryEntryOverhead + 8 + expiryEntryOverhead
+	storage := NewMemoryStorageWithOptions(MemoryOptions{Shards: 1, MaxBytes: 10 * perKey})
+
+	for i := 0; i < 100; i++ {
+		_, _ = storage.IncrementRequest(ctx, fmt.Sprintf("ip:%05d", i))
+	}
+
+	shard := storage.shards[0]
+	assert.LessOrEqual(t, shard.bytes+len(shard.expiries)*expiryEntryOverhead, 10*perKey)
+	assert.Len(t, shard.entries, 10)
+	assert.Len(t, shard.expiries, 10)
+}
+
+func TestMemoryLimits_ByteBudgetCountsGrowthOfExistingKeys(t *testing.T) {
+	ctx := context.Background()
+	perKey := memoryEntryOverhead + 8 + expiryEntryOverhead
+	storage := NewMemoryStorageWithOptions(MemoryOptions{Shards: 1, MaxBytes: 10 * perKey})
+
+	for i := 0; i < 10; i++ {
+		_, _ = storage.IncrementRequest(ctx, fmt.Sprintf("ip:%05d", i))
+	}
+	// Nenhuma chave nova: só reincidências e bloqueios agendados nas chaves existentes
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("ip:%05d", i)
+		_, _ = storage.IncrementStrikes(ctx, key, time.Hour)
+		assert.NoError(t, storage.BlockKey(ctx, key, time.Hour))
+	}
+
+	shard := storage.shards[0]
+	assert.LessOrEqual(t, shard.bytes+len(shard.expiries)*expiryEntryOverhead, 10*perKey)
+	assert.Len(t, shard.scheduled, len(shard.expiries))
+}
+
+func TestMemoryLimits_RemovedKeysFreeBudget(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorageWithOptions(MemoryOptions{Shards: 1, MaxEntries: 10})
+
+	_, _ = storage.IncrementRequest(ctx, "ip:1")
+	assert.NoError(t, storage.ResetKey(ctx, "ip:1"))
+	assert.NoError(t, storage.BlockKey(ctx, "ip:2", time.Hour))
+	assert.NoError(t, storage.UnblockKey(ctx, "ip:2"))
+
+	shard := storage.shards[0]
+	assert.Empty(t, shard.entries)
+	assert.Equal(t, 0, shard.bytes)
+}
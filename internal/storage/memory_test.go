@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -8,61 +11,168 @@ import (
 )
 
 func TestMemoryRateLimiterStorage(t *testing.T) {
-	storage := NewMemoryStorage()
+	ctx := context.Background()
+	now := time.Now()
+	storage := NewMemoryStorageWithOptions(MemoryOptions{Clock: ClockFunc(func() time.Time { return now })})
 
 	ipKey := "rate_limiter:ip:192.168.1.100"
 	tokenKey := "rate_limiter:token:abc123"
 
 	// Teste: IncrementRequest e GetRequestCount
-	count, err := storage.IncrementRequest(ipKey)
+	count, err := storage.IncrementRequest(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
 
-	count, err = storage.GetRequestCount(ipKey)
+	count, err = storage.GetRequestCount(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
 
 	// Teste: BlockKey e IsBlocked
-	err = storage.BlockKey(ipKey, 5*time.Second)
+	err = storage.BlockKey(ctx, ipKey, 5*time.Second)
 	assert.NoError(t, err)
 
-	blocked, err := storage.IsBlocked(ipKey)
+	blocked, err := storage.IsBlocked(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.True(t, blocked)
 
-	// Esperar 6 segundos para verificar se o bloqueio expira
-	time.Sleep(6 * time.Second)
-
-	blocked, err = storage.IsBlocked(ipKey)
-	assert.NoError(t, err)
-	assert.False(t, blocked)
-
 	// Teste: SetBlockDuration e GetBlockDuration
-	err = storage.SetBlockDuration(ipKey, 10*time.Second)
+	err = storage.SetBlockDuration(ctx, ipKey, 10*time.Second)
 	assert.NoError(t, err)
 
-	blockDuration, err := storage.GetBlockDuration(ipKey)
+	blockDuration, err := storage.GetBlockDuration(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 10*time.Second, blockDuration)
 
+	// Avançar 6 segundos para verificar se o bloqueio expira
+	now = now.Add(6 * time.Second)
+
+	blocked, err = storage.IsBlocked(ctx, ipKey)
+	assert.NoError(t, err)
+	assert.False(t, blocked)
+
 	// Teste: ResetKey
-	err = storage.ResetKey(ipKey)
+	err = storage.ResetKey(ctx, ipKey)
 	assert.NoError(t, err)
 
-	count, err = storage.GetRequestCount(ipKey)
+	count, err = storage.GetRequestCount(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, count)
 
 	// Teste: IncrementRequest para um Token
-	count, err = storage.IncrementRequest(tokenKey)
+	count, err = storage.IncrementRequest(ctx, tokenKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
 
 	// Teste: BlockKey para um Token
-	err = storage.BlockKey(tokenKey, 3*time.Second)
+	err = storage.BlockKey(ctx, tokenKey, 3*time.Second)
+	assert.NoError(t, err)
+
+	blocked, err = storage.IsBlocked(ctx, tokenKey)
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+}
+
+func TestMemoryRateLimiterStorage_Inspection(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	storage.IncrementRequest(ctx, "192.168.1.1")
+	storage.BlockKey(ctx, "192.168.1.1", time.Minute)
+	storage.BlockKey(ctx, "192.168.1.2", time.Minute)
+	storage.BlockKey(ctx, "192.168.1.3", -time.Second)
+
+	// Bloqueios expirados não devem aparecer na listagem
+	keys, err := storage.ListBlockedKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.168.1.1", "192.168.1.2"}, keys)
+
+	info, err := storage.InspectKey(ctx, "192.168.1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.Count)
+	assert.True(t, info.Blocked)
+	assert.InDelta(t, time.Minute.Seconds(), info.BlockRemaining.Seconds(), 1)
+
+	err = storage.UnblockKey(ctx, "192.168.1.1")
 	assert.NoError(t, err)
 
-	blocked, err = storage.IsBlocked(tokenKey)
+	info, err = storage.InspectKey(ctx, "192.168.1.1")
 	assert.NoError(t, err)
+	assert.False(t, info.Blocked)
+	assert.Equal(t, 1, info.Count)
+}
+
+func TestMemoryRateLimiterStorage_ReblockOutlivesStaleExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	storage := NewMemoryStorageWithOptions(MemoryOptions{Shards: 1, Clock: clock})
+
+	assert.NoError(t, storage.BlockKey(ctx, "ip:1", 20*time.Millisecond))
+	assert.NoError(t, storage.BlockKey(ctx, "ip:1", time.Minute))
+	clock.Advance(40 * time.Millisecond)
+
+	// A entrada antiga do heap venceu, mas não pode derrubar o bloqueio novo
+	blocked, _ := storage.IsBlocked(ctx, "ip:1")
 	assert.True(t, blocked)
+	duration, _ := storage.GetBlockDuration(ctx, "ip:1")
+	assert.Equal(t, time.Minute, duration)
+}
+
+func TestMemoryRateLimiterStorage_ExpiredBlocksArePurged(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	storage := NewMemoryStorageWithOptions(MemoryOptions{Shards: 4, Clock: clock})
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		assert.NoError(t, storage.BlockKey(ctx, key, 10*time.Millisecond))
+	}
+	clock.Advance(20 * time.Millisecond)
+
+	keys, _ := storage.ListBlockedKeys(ctx)
+	assert.Empty(t, keys)
+	for _, shard := range storage.shards {
+		assert.Empty(t, shard.blocked)
+		assert.Empty(t, shard.blockDurations)
+		assert.Empty(t, shard.expiries)
+	}
+}
+
+func TestMemoryRateLimiterStorage_ExpiredCountersArePurged(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	storage := NewMemoryStorageWithOptions(MemoryOptions{Shards: 4, Clock: ClockFunc(func() time.Time { return now })})
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		_, _ = storage.IncrementRequest(ctx, key)
+	}
+	now = now.Add(time.Minute)
+
+	// Contadores de chaves que nunca mais aparecem também saem da memória
+	keys, _ := storage.ListBlockedKeys(ctx)
+	assert.Empty(t, keys)
+	for _, shard := range storage.shards {
+		assert.Empty(t, shard.requests)
+		assert.Empty(t, shard.expiries)
+	}
+}
+
+func TestMemoryRateLimiterStorage_ConcurrentIncrements(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = storage.IncrementRequest(ctx, fmt.Sprintf("ip:%d", j%10))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for j := 0; j < 10; j++ {
+		count, _ := storage.GetRequestCount(ctx, fmt.Sprintf("ip:%d", j))
+		assert.Equal(t, 500, count)
+	}
 }
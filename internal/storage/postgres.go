@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresSchema é aplicado na criação do storage. Todas as expirações usam o
+// relógio do banco (now()), para que instâncias com relógios diferentes concordem.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS rate_limiter_counters (
+	key          TEXT PRIMARY KEY,
+	count        INTEGER NOT NULL,
+	window_start TIMESTAMPTZ NOT NULL,
+	expires_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limiter_counters_expires_at ON rate_limiter_counters (expires_at);
+
+CREATE TABLE IF NOT EXISTS rate_limiter_blocks (
+	key         TEXT PRIMARY KEY,
+	expires_at  TIMESTAMPTZ NOT NULL,
+	duration_ms BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limiter_blocks_expires_at ON rate_limiter_blocks (expires_at);
+
+CREATE TABLE IF NOT EXISTS rate_limiter_strikes (
+	key        TEXT PRIMARY KEY,
+	count      INTEGER NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limiter_strikes_expires_at ON rate_limiter_strikes (expires_at);
+`
+
+// incrementQuery soma ao contador da janela atual ou abre uma nova janela se a anterior expirou,
+// com a mesma janela fixa usada pelo Redis.
+const incrementQuery = `
+INSERT INTO rate_limiter_counters AS c (key, count, window_start, expires_at)
+VALUES ($1, $2, now(), now() + $3::bigint * interval '1 millisecond')
+ON CONFLICT (key) DO UPDATE SET
+	count        = CASE WHEN c.expires_at <= now() THEN EXCLUDED.count ELSE c.count + EXCLUDED.count END,
+	window_start = CASE WHEN c.expires_at <= now() THEN EXCLUDED.window_start ELSE c.window_start END,
+	expires_at   = CASE WHEN c.expires_at <= now() THEN EXCLUDED.expires_at ELSE c.expires_at END
+RETURNING count`
+
+// strikesQuery soma uma violação (recomeçando do zero se as anteriores já foram
+// esquecidas) e renova a expiração a cada nova violação.
+const strikesQuery = `
+INSERT INTO rate_limiter_strikes AS s (key, count, expires_at)
+VALUES ($1, 1, now() + $2::bigint * interval '1 millisecond')
+ON CONFLICT (key) DO UPDATE SET
+	count      = CASE WHEN s.expires_at <= now() THEN 1 ELSE s.count + 1 END,
+	expires_at = EXCLUDED.expires_at
+RETURNING count`
+
+// defaultCleanupInterval é o intervalo de limpeza quando nenhum válido é informado.
+const defaultCleanupInterval = time.Minute
+
+type PostgresRateLimiterStorage struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStorage conecta ao banco e cria as tabelas se ainda não existirem.
+func NewPostgresStorage(ctx context.Context, dsn string) (*PostgresRateLimiterStorage, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pool.Exec(ctx, postgresSchema); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &PostgresRateLimiterStorage{pool: pool}, nil
+}
+
+func (p *PostgresRateLimiterStorage) Close() {
+	p.pool.Close()
+}
+
+func (p *PostgresRateLimiterStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return p.IncrementRequestBy(ctx, key, 1)
+}
+
+func (p *PostgresRateLimiterStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	var count int
+	err := p.pool.QueryRow(ctx, incrementQuery, key, delta, counterWindow.Milliseconds()).Scan(&count)
+	return count, err
+}
+
+func (p *PostgresRateLimiterStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	var count int
+	err := p.pool.QueryRow(ctx,
+		`SELECT count FROM rate_limiter_counters WHERE key = $1 AND expires_at > now()`, key,
+	).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return count, err
+}
+
+func (p *PostgresRateLimiterStorage) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO rate_limiter_blocks (key, expires_at, duration_ms)
+VALUES ($1, now() + $2::bigint * interval '1 millisecond', $2)
+ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at, duration_ms = EXCLUDED.duration_ms`,
+		key, duration.Milliseconds())
+	return err
+}
+
+func (p *PostgresRateLimiterStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	var blocked bool
+	err := p.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM rate_limiter_blocks WHERE key = $1 AND expires_at > now())`, key,
+	).Scan(&blocked)
+	return blocked, err
+}
+
+func (p *PostgresRateLimiterStorage) ResetKey(ctx context.Context, key string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM rate_limiter_counters WHERE key = $1`, key)
+	return err
+}
+
+func (p *PostgresRateLimiterStorage) GetBlockDuration(ctx context.Context, key string) (time.Duration, error) {
+	var durationMs int64
+	err := p.pool.QueryRow(ctx,
+		`SELECT duration_ms FROM rate_limiter_blocks WHERE key = $1 AND expires_at > now()`, key,
+	).Scan(&durationMs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return time.Duration(durationMs) * time.Millisecond, err
+}
+
+func (p *PostgresRateLimiterStorage) GetBlockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	var remainingMs int64
+	err := p.pool.QueryRow(ctx, `
+SELECT (EXTRACT(EPOCH FROM expires_at - now()) * 1000)::bigint
+FROM rate_limiter_blocks WHERE key = $1 AND expires_at > now()`, key,
+	).Scan(&remainingMs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return time.Duration(remainingMs) * time.Millisecond, err
+}
+
+// SetBlockDuration grava a duração sem bloquear: uma linha nova nasce já expirada.
+func (p *PostgresRateLimiterStorage) SetBlockDuration(ctx context.Context, key string, duration time.Duration) error {
+	_, err := p.pool.Exec(ctx, `
+INSERT INTO rate_limiter_blocks (key, expires_at, duration_ms)
+VALUES ($1, now(), $2)
+ON CONFLICT (key) DO UPDATE SET duration_ms = EXCLUDED.duration_ms`,
+		key, duration.Milliseconds())
+	return err
+}
+
+func (p *PostgresRateLimiterStorage) IncrementStrikes(ctx context.Context, key string, decay time.Duration) (int, error) {
+	var strikes int
+	err := p.pool.QueryRow(ctx, strikesQuery, key, decay.Milliseconds()).Scan(&strikes)
+	return strikes, err
+}
+
+func (p *PostgresRateLimiterStorage) UnblockKey(ctx context.Context, key string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM rate_limiter_blocks WHERE key = $1`, key)
+	return err
+}
+
+func (p *PostgresRateLimiterStorage) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	rows, err := p.pool.Query(ctx,
+		`SELECT key FROM rate_limiter_blocks WHERE expires_at > now() ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if keys == nil {
+		keys = []string{}
+	}
+	return keys, err
+}
+
+func (p *PostgresRateLimiterStorage) InspectKey(ctx context.Context, key string) (KeyInfo, error) {
+	var countMs, blockMs *int64
+	var count *int
+	err := p.pool.QueryRow(ctx, `
+SELECT
+	(SELECT count FROM rate_limiter_counters WHERE key = $1 AND expires_at > now()),
+	(SELECT (EXTRACT(EPOCH FROM expires_at - now()) * 1000)::bigint
+	   FROM rate_limiter_counters WHERE key = $1 AND expires_at > now()),
+	(SELECT (EXTRACT(EPOCH FROM expires_at - now()) * 1000)::bigint
+	   FROM rate_limiter_blocks WHERE key = $1 AND expires_at > now())`, key,
+	).Scan(&count, &countMs, &blockMs)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	info := KeyInfo{Key: key}
+	if count != nil {
+		info.Count = *count
+	}
+	if countMs != nil {
+		info.CountTTL = time.Duration(*countMs) * time.Millisecond
+	}
+	if blockMs != nil && *blockMs > 0 {
+		info.Blocked = true
+		info.BlockRemaining = time.Duration(*blockMs) * time.Millisecond
+	}
+	return info, nil
+}
+
+func (p *PostgresRateLimiterStorage) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	return p.pool.Ping(ctx)
+}
+
+// Cleanup remove contadores, bloqueios e violações expirados. As consultas já ignoram linhas
+// expiradas; a limpeza existe apenas para as tabelas não crescerem sem limite.
+func (p *PostgresRateLimiterStorage) Cleanup(ctx context.Context) (int64, error) {
+	var removed int64
+	for _, table := range []string{"rate_limiter_counters", "rate_limiter_blocks", "rate_limiter_strikes"} {
+		tag, err := p.pool.Exec(ctx, `DELETE FROM `+table+` WHERE expires_at <= now()`)
+		if err != nil {
+			return removed, err
+		}
+		removed += tag.RowsAffected()
+	}
+	return removed, nil
+}
+
+// RunCleanup executa Cleanup a cada intervalo até o contexto ser cancelado.
+// Intervalos não positivos usam defaultCleanupInterval.
+func (p *PostgresRateLimiterStorage) RunCleanup(ctx context.Context, interval time.Duration, onError func(error)) {
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Cleanup(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
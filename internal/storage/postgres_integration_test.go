@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func setupPostgresContainer(t *testing.T) (*PostgresRateLimiterStorage, func()) {
+	ctx := context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        "postgres:16-alpine",
+		ExposedPorts: []string{"5432/tcp"},
+		Env: map[string]string{
+			"POSTGRES_USER":     "rate_limiter",
+			"POSTGRES_PASSWORD": "rate_limiter",
+			"POSTGRES_DB":       "rate_limiter",
+		},
+		// O Postgres reinicia uma vez depois do initdb
+		WaitingFor: wait.ForLog("database system is ready to accept connections").WithOccurrence(2),
+	}
+
+	postgresContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	assert.NoError(t, err)
+
+	host, err := postgresContainer.Host(ctx)
+	assert.NoError(t, err)
+	port, err := postgresContainer.MappedPort(ctx, "5432")
+	assert.NoError(t, err)
+
+	dsn := fmt.Sprintf("postgres://rate_limiter:rate_limiter@%s:%s/rate_limiter?sslmode=disable", host, port.Port())
+	postgresStorage, err := NewPostgresStorage(ctx, dsn)
+	assert.NoError(t, err)
+
+	cleanup := func() {
+		postgresStorage.Close()
+		postgresContainer.Terminate(ctx)
+	}
+
+	return postgresStorage, cleanup
+}
+
+func TestPostgres_Ping(t *testing.T) {
+	ctx := context.Background()
+	postgresStorage, cleanup := setupPostgresContainer(t)
+	defer cleanup()
+
+	assert.NoError(t, postgresStorage.Ping(ctx), "O Postgres deveria responder ao Ping() corretamente")
+}
+
+func TestPostgres_IncrementRequest(t *testing.T) {
+	ctx := context.Background()
+	postgresStorage, cleanup := setupPostgresContainer(t)
+	defer cleanup()
+
+	key := "test_ip"
+	count, err := postgresStorage.IncrementRequest(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = postgresStorage.IncrementRequestBy(ctx, key, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	count, err = postgresStorage.GetRequestCount(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	assert.NoError(t, postgresStorage.ResetKey(ctx, key))
+	count, _ = postgresStorage.GetRequestCount(ctx, key)
+	assert.Equal(t, 0, count)
+}
+
+func TestPostgres_BlockKey(t *testing.T) {
+	ctx := context.Background()
+	postgresStorage, cleanup := setupPostgresContainer(t)
+	defer cleanup()
+
+	key := "blocked_ip"
+	assert.NoError(t, postgresStorage.BlockKey(ctx, key, 2*time.Second))
+
+	blocked, err := postgresStorage.IsBlocked(ctx, key)
+	assert.NoError(t, err)
+	assert.True(t, blocked, "A chave deveria estar bloqueada")
+
+	duration, err := postgresStorage.GetBlockDuration(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, duration)
+
+	time.Sleep(3 * time.Second)
+
+	blocked, err = postgresStorage.IsBlocked(ctx, key)
+	assert.NoError(t, err)
+	assert.False(t, blocked, "A chave deveria ter sido desbloqueada após o tempo definido")
+}
+
+func TestPostgres_InspectAndUnblock(t *testing.T) {
+	ctx := context.Background()
+	postgresStorage, cleanup := setupPostgresContainer(t)
+	defer cleanup()
+
+	_, _ = postgresStorage.IncrementRequest(ctx, "ip:1")
+	assert.NoError(t, postgresStorage.BlockKey(ctx, "ip:1", time.Minute))
+
+	info, err := postgresStorage.InspectKey(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.Count)
+	assert.True(t, info.CountTTL > 0 && info.CountTTL <= time.Minute)
+	assert.True(t, info.Blocked)
+	assert.True(t, info.BlockRemaining > 0 && info.BlockRemaining <= time.Minute)
+
+	keys, err := postgresStorage.ListBlockedKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ip:1"}, keys)
+
+	assert.NoError(t, postgresStorage.UnblockKey(ctx, "ip:1"))
+	blocked, _ := postgresStorage.IsBlocked(ctx, "ip:1")
+	assert.False(t, blocked)
+}
+
+func TestPostgres_CleanupRemovesExpiredRows(t *testing.T) {
+	ctx := context.Background()
+	postgresStorage, cleanup := setupPostgresContainer(t)
+	defer cleanup()
+
+	assert.NoError(t, postgresStorage.BlockKey(ctx, "expired", 10*time.Millisecond))
+	assert.NoError(t, postgresStorage.BlockKey(ctx, "active", time.Minute))
+	time.Sleep(50 * time.Millisecond)
+
+	removed, err := postgresStorage.Cleanup(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	keys, _ := postgresStorage.ListBlockedKeys(ctx)
+	assert.Equal(t, []string{"active"}, keys)
+}
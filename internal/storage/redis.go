@@ -2,48 +2,187 @@ package storage
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+
+	blockKeyPrefix      = "rate_limiter:block:"
+	invalidationChannel = "rate_limiter:invalidate"
+	countKeyPrefix      = "rate_limiter:count:"
+	strikesKeyPrefix    = "rate_limiter:strikes:"
+
+	counterWindow = time.Minute
+)
+
+// incrementScript incrementa o contador e só define a expiração na abertura da
+// janela (ou se a chave perdeu o TTL), em uma única ida ao Redis.
+var incrementScript = redis.NewScript(`
+local count = redis.call('INCRBY', KEYS[1], ARGV[2])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// strikesScript soma uma violação e renova a expiração a cada nova violação.
+var strikesScript = redis.NewScript(`
+local strikes = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return strikes
+`)
+
+type RedisConfig struct {
+	Mode             string
+	Addrs            []string
+	MasterName       string
+	Username         string
+	Password         string
+	SentinelPassword string
+	DB               int
+	TLSConfig        *tls.Config
+
+	// Ajustes do pool de conexões; zero mantém o padrão do go-redis.
+	PoolSize     int
+	MinIdleConns int
+	PoolTimeout  time.Duration
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	MaxRetries   int
+}
+
 type RedisRateLimiterStorage struct {
-	client *redis.Client
-	ctx    context.Context
+	client redis.UniversalClient
+	keys   redisKeys
 }
 
 func NewRedisStorage(redisAddr, redisPassword string, redisDB int) *RedisRateLimiterStorage {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
+	redisStorage, _ := NewRedisStorageFromConfig(RedisConfig{
+		Mode:     RedisModeStandalone,
+		Addrs:    []string{redisAddr},
 		Password: redisPassword,
 		DB:       redisDB,
 	})
-	return &RedisRateLimiterStorage{
-		client: rdb,
-		ctx:    context.Background(),
+	return redisStorage
+}
+
+// NewRedisStorageFromConfig cria o storage para um nó único, Sentinel ou Cluster.
+// No modo sentinel Addrs são os endereços dos sentinels; no cluster, os nós semente.
+func NewRedisStorageFromConfig(cfg RedisConfig) (*RedisRateLimiterStorage, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("nenhum endereço do Redis configurado")
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addrs,
+		MasterName:       cfg.MasterName,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelPassword: cfg.SentinelPassword,
+		DB:               cfg.DB,
+		TLSConfig:        cfg.TLSConfig,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		PoolTimeout:      cfg.PoolTimeout,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+		MaxRetries:       cfg.MaxRetries,
+		// Sem isso o go-redis ignora o deadline do contexto e espera o ReadTimeout inteiro
+		ContextTimeoutEnabled: true,
+	}
+
+	var client redis.UniversalClient
+	switch cfg.Mode {
+	case "", RedisModeStandalone:
+		client = redis.NewClient(opts.Simple())
+	case RedisModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, errors.New("modo sentinel exige o nome do master")
+		}
+		client = redis.NewFailoverClient(opts.Failover())
+	case RedisModeCluster:
+		client = redis.NewClusterClient(opts.Cluster())
+	default:
+		return nil, fmt.Errorf("modo do Redis desconhecido: %q", cfg.Mode)
 	}
+
+	return &RedisRateLimiterStorage{client: client, keys: redisKeys{hashTag: cfg.Mode == RedisModeCluster}}, nil
+}
+
+// redisKeys monta os nomes das chaves no Redis. Só o Cluster usa hash tag ({key}),
+// para que contador, bloqueio e violações de uma mesma chave caiam no mesmo slot;
+// nos demais modos fica o layout original (contador na própria chave), que continua
+// valendo para os dados já gravados.
+type redisKeys struct {
+	hashTag bool
 }
 
-func (r *RedisRateLimiterStorage) Ping() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (k redisKeys) count(key string) string {
+	if k.hashTag {
+		return countKeyPrefix + "{" + key + "}"
+	}
+	return key
+}
+
+func (k redisKeys) block(key string) string {
+	return blockKeyPrefix + k.tag(key)
+}
+
+func (k redisKeys) strikes(key string) string {
+	return strikesKeyPrefix + k.tag(key)
+}
+
+func (k redisKeys) fromBlock(redisKey string) string {
+	key := strings.TrimPrefix(redisKey, blockKeyPrefix)
+	if k.hashTag {
+		key = strings.TrimSuffix(strings.TrimPrefix(key, "{"), "}")
+	}
+	return key
+}
+
+func (k redisKeys) tag(key string) string {
+	if k.hashTag {
+		return "{" + key + "}"
+	}
+	return key
+}
+
+func (r *RedisRateLimiterStorage) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
 	_, err := r.client.Ping(ctx).Result()
 	return err
 }
 
-func (r *RedisRateLimiterStorage) IncrementRequest(key string) (int, error) {
-	count, err := r.client.Incr(r.ctx, key).Result()
+func (r *RedisRateLimiterStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return r.IncrementRequestBy(ctx, key, 1)
+}
+
+func (r *RedisRateLimiterStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	count, err := incrementScript.Run(ctx, r.client, []string{r.keys.count(key)}, counterWindow.Milliseconds(), delta).Int()
 	if err != nil {
 		return 0, err
 	}
-	r.client.Expire(r.ctx, key, time.Minute)
-	return int(count), nil
+	return count, nil
 }
 
-func (r *RedisRateLimiterStorage) GetRequestCount(key string) (int, error) {
-	count, err := r.client.Get(r.ctx, key).Result()
+func (r *RedisRateLimiterStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	count, err := r.client.Get(ctx, r.keys.count(key)).Result()
 	if err == redis.Nil {
 		return 0, nil
 	} else if err != nil {
@@ -53,33 +192,158 @@ func (r *RedisRateLimiterStorage) GetRequestCount(key string) (int, error) {
 	return parsedCount, nil
 }
 
-func (r *RedisRateLimiterStorage) BlockKey(key string, duration time.Duration) error {
-	return r.client.Set(r.ctx, "rate_limiter:block:"+key, 1, duration).Err()
+// BlockKey guarda a duração do bloqueio, em milissegundos, como valor da própria chave
+// de bloqueio, para que GetBlockDuration devolva o que foi aplicado.
+func (r *RedisRateLimiterStorage) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	return r.client.Set(ctx, r.keys.block(key), duration.Milliseconds(), duration).Err()
 }
 
-func (r *RedisRateLimiterStorage) IsBlocked(key string) (bool, error) {
-	exists, err := r.client.Exists(r.ctx, "rate_limiter:block:"+key).Result()
+func (r *RedisRateLimiterStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	exists, err := r.client.Exists(ctx, r.keys.block(key)).Result()
 	if err != nil {
 		return false, err
 	}
 	return exists > 0, nil
 }
 
-func (r *RedisRateLimiterStorage) ResetKey(key string) error {
-	return r.client.Del(r.ctx, key).Err()
+func (r *RedisRateLimiterStorage) ResetKey(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.keys.count(key)).Err()
 }
 
-func (r *RedisRateLimiterStorage) GetBlockDuration(key string) (time.Duration, error) {
-	duration, err := r.client.Get(r.ctx, "rate_limiter:block:"+key).Result()
+func (r *RedisRateLimiterStorage) GetBlockDuration(ctx context.Context, key string) (time.Duration, error) {
+	duration, err := r.client.Get(ctx, r.keys.block(key)).Result()
 	if err == redis.Nil {
 		return 0, nil
 	} else if err != nil {
 		return 0, err
 	}
-	blockTime, _ := strconv.Atoi(duration)
-	return time.Duration(blockTime) * time.Second, nil
+	durationMs, _ := strconv.ParseInt(duration, 10, 64)
+	return time.Duration(durationMs) * time.Millisecond, nil
+}
+
+// GetBlockRemaining usa o PTTL da chave de bloqueio, que é a própria expiração do bloqueio.
+func (r *RedisRateLimiterStorage) GetBlockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, r.keys.block(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL devolve valores negativos quando a chave não existe ou não tem expiração
+	return max(ttl, 0), nil
+}
+
+// SetBlockDuration troca a duração registrada de um bloqueio existente sem mexer no TTL;
+// sem bloqueio não há onde guardar a duração e nada é gravado.
+func (r *RedisRateLimiterStorage) SetBlockDuration(ctx context.Context, key string, duration time.Duration) error {
+	err := r.client.SetArgs(ctx, r.keys.block(key), duration.Milliseconds(), redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
+
+func (r *RedisRateLimiterStorage) IncrementStrikes(ctx context.Context, key string, decay time.Duration) (int, error) {
+	return strikesScript.Run(ctx, r.client, []string{r.keys.strikes(key)}, decay.Milliseconds()).Int()
 }
 
-func (r *RedisRateLimiterStorage) SetBlockDuration(key string, duration time.Duration) error {
-	return r.client.Set(r.ctx, "rate_limiter:block:"+key, int(duration.Seconds()), duration).Err()
+// UnblockKey remove o bloqueio e avisa as instâncias com cache local de bloqueios.
+func (r *RedisRateLimiterStorage) UnblockKey(ctx context.Context, key string) error {
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.keys.block(key))
+		pipe.Publish(ctx, invalidationChannel, key)
+		return nil
+	})
+	return err
+}
+
+// SubscribeInvalidations bloqueia até o contexto ser cancelado, chamando handler
+// para cada chave desbloqueada por qualquer instância.
+func (r *RedisRateLimiterStorage) SubscribeInvalidations(ctx context.Context, handler func(key string)) error {
+	pubsub := r.client.Subscribe(ctx, invalidationChannel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			handler(msg.Payload)
+		}
+	}
+}
+
+func (r *RedisRateLimiterStorage) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	redisKeys, err := r.scan(ctx, blockKeyPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(redisKeys))
+	for _, redisKey := range redisKeys {
+		keys = append(keys, r.keys.fromBlock(redisKey))
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// scan percorre todas as chaves do padrão; no Cluster cada master é varrido separadamente.
+func (r *RedisRateLimiterStorage) scan(ctx context.Context, pattern string) ([]string, error) {
+	cluster, isCluster := r.client.(*redis.ClusterClient)
+	if !isCluster {
+		return scanNode(ctx, r.client, pattern)
+	}
+
+	var mu sync.Mutex
+	var keys []string
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		nodeKeys, err := scanNode(ctx, node, pattern)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		keys = append(keys, nodeKeys...)
+		mu.Unlock()
+		return nil
+	})
+	return keys, err
+}
+
+func scanNode(ctx context.Context, client redis.Cmdable, pattern string) ([]string, error) {
+	var keys []string
+	iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+func (r *RedisRateLimiterStorage) InspectKey(ctx context.Context, key string) (KeyInfo, error) {
+	pipe := r.client.Pipeline()
+	count := pipe.Get(ctx, r.keys.count(key))
+	countTTL := pipe.PTTL(ctx, r.keys.count(key))
+	blockTTL := pipe.PTTL(ctx, r.keys.block(key))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return KeyInfo{}, err
+	}
+
+	info := KeyInfo{Key: key}
+	if value, err := count.Result(); err == nil {
+		info.Count, _ = strconv.Atoi(value)
+	}
+	// PTTL devolve valores negativos quando a chave não existe ou não tem expiração
+	if ttl := countTTL.Val(); ttl > 0 {
+		info.CountTTL = ttl
+	}
+	if ttl := blockTTL.Val(); ttl > 0 {
+		info.Blocked = true
+		info.BlockRemaining = ttl
+	}
+	return info, nil
 }
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisStorageFromConfig_Modes(t *testing.T) {
+	standalone, err := NewRedisStorageFromConfig(RedisConfig{Addrs: []string{"localhost:6379"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &redis.Client{}, standalone.client)
+
+	sentinel, err := NewRedisStorageFromConfig(RedisConfig{
+		Mode:       RedisModeSentinel,
+		Addrs:      []string{"sentinel-1:26379", "sentinel-2:26379"},
+		MasterName: "mymaster",
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &redis.Client{}, sentinel.client)
+
+	cluster, err := NewRedisStorageFromConfig(RedisConfig{
+		Mode:  RedisModeCluster,
+		Addrs: []string{"node-1:6379", "node-2:6379", "node-3:6379"},
+	})
+	assert.NoError(t, err)
+	assert.IsType(t, &redis.ClusterClient{}, cluster.client)
+}
+
+func TestNewRedisStorageFromConfig_PoolAndACL(t *testing.T) {
+	redisStorage, err := NewRedisStorageFromConfig(RedisConfig{
+		Addrs:       []string{"localhost:6379"},
+		Username:    "rate-limiter",
+		Password:    "s3cr3t",
+		PoolSize:    50,
+		ReadTimeout: 200 * time.Millisecond,
+		MaxRetries:  5,
+	})
+	assert.NoError(t, err)
+
+	opts := redisStorage.client.(*redis.Client).Options()
+	assert.Equal(t, "rate-limiter", opts.Username)
+	assert.Equal(t, "s3cr3t", opts.Password)
+	assert.Equal(t, 50, opts.PoolSize)
+	assert.Equal(t, 200*time.Millisecond, opts.ReadTimeout)
+	assert.Equal(t, 5, opts.MaxRetries)
+	assert.True(t, opts.ContextTimeoutEnabled)
+}
+
+func TestNewRedisStorageFromConfig_Invalid(t *testing.T) {
+	_, err := NewRedisStorageFromConfig(RedisConfig{Mode: RedisModeSentinel, Addrs: []string{"sentinel-1:26379"}})
+	assert.ErrorContains(t, err, "master")
+
+	_, err = NewRedisStorageFromConfig(RedisConfig{Mode: "replicated", Addrs: []string{"localhost:6379"}})
+	assert.Error(t, err)
+
+	_, err = NewRedisStorageFromConfig(RedisConfig{Mode: RedisModeCluster})
+	assert.Error(t, err)
+}
+
+func TestRedisKeys_ShareHashSlot(t *testing.T) {
+	keys := redisKeys{hashTag: true}
+	for _, key := range []string{"192.168.1.1", "token123", "2001:db8::1"} {
+		assert.Equal(t, "rate_limiter:count:{"+key+"}", keys.count(key))
+		assert.Equal(t, "rate_limiter:block:{"+key+"}", keys.block(key))
+		assert.Equal(t, "rate_limiter:strikes:{"+key+"}", keys.strikes(key))
+		assert.Equal(t, key, keys.fromBlock(keys.block(key)))
+
+		// Contador, bloqueio e violações precisam cair no mesmo slot do Cluster
+		assert.Equal(t, hashSlot(keys.count(key)), hashSlot(keys.block(key)))
+		assert.Equal(t, hashSlot(keys.count(key)), hashSlot(keys.strikes(key)))
+	}
+}
+
+func TestRedisKeys_LegacyLayoutOutsideCluster(t *testing.T) {
+	standalone, err := NewRedisStorageFromConfig(RedisConfig{Addrs: []string{"localhost:6379"}})
+	assert.NoError(t, err)
+	cluster, err := NewRedisStorageFromConfig(RedisConfig{Mode: RedisModeCluster, Addrs: []string{"node-1:6379"}})
+	assert.NoError(t, err)
+
+	// Fora do Cluster as chaves mantêm o layout anterior, sem migração
+	assert.Equal(t, "rate_limiter:ip:192.168.1.1", standalone.keys.count("rate_limiter:ip:192.168.1.1"))
+	assert.Equal(t, "rate_limiter:block:rate_limiter:ip:192.168.1.1", standalone.keys.block("rate_limiter:ip:192.168.1.1"))
+	assert.Equal(t, "rate_limiter:strikes:ip:1", standalone.keys.strikes("ip:1"))
+	assert.Equal(t, "ip:1", standalone.keys.fromBlock("rate_limiter:block:ip:1"))
+
+	assert.Equal(t, "rate_limiter:block:{ip:1}", cluster.keys.block("ip:1"))
+}
+
+// hashSlot reproduz o cálculo de slot do Redis Cluster (CRC16 da hash tag mod 16384)
+func hashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
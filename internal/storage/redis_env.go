@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"rate-limiter/config"
+)
+
+// RedisConfigFromEnv monta a RedisConfig a partir das variáveis REDIS_* (modo, endereços,
+// ACL, TLS e pool), para que o serviço e o ratelimitctl se conectem ao Redis da mesma forma.
+func RedisConfigFromEnv(cfg config.Config) (RedisConfig, error) {
+	pool := cfg.RedisPool
+	redisCfg := RedisConfig{
+		Mode:             cfg.RedisMode,
+		Addrs:            cfg.RedisAddrs(),
+		MasterName:       cfg.RedisMasterName,
+		Username:         cfg.RedisUsername,
+		Password:         cfg.RedisPassword,
+		SentinelPassword: cfg.RedisSentinelPassword,
+		DB:               cfg.RedisDB,
+		PoolSize:         pool.Size,
+		MinIdleConns:     pool.MinIdleConns,
+		PoolTimeout:      time.Duration(pool.PoolTimeoutMs) * time.Millisecond,
+		DialTimeout:      time.Duration(pool.DialTimeoutMs) * time.Millisecond,
+		ReadTimeout:      time.Duration(pool.ReadTimeoutMs) * time.Millisecond,
+		WriteTimeout:     time.Duration(pool.WriteTimeoutMs) * time.Millisecond,
+		MaxRetries:       pool.MaxRetries,
+	}
+
+	if tlsCfg := cfg.RedisTLS; tlsCfg.Enabled {
+		tlsConfig, err := NewRedisTLSConfig(RedisTLSOptions{
+			CAFile:             tlsCfg.CAFile,
+			CertFile:           tlsCfg.CertFile,
+			KeyFile:            tlsCfg.KeyFile,
+			ServerName:         tlsCfg.ServerName,
+			InsecureSkipVerify: tlsCfg.InsecureSkipVerify,
+		})
+		if err != nil {
+			return RedisConfig{}, err
+		}
+		redisCfg.TLSConfig = tlsConfig
+	}
+	return redisCfg, nil
+}
+
+// NewShardedRedisStorage cria um RedisRateLimiterStorage standalone por endereço de
+// redisCfg.Addrs, com as mesmas credenciais, TLS e pool, e distribui as chaves entre eles.
+func NewShardedRedisStorage(redisCfg RedisConfig, opts ShardedOptions) (*ShardedStorage, error) {
+	nodes := make([]ShardNode, 0, len(redisCfg.Addrs))
+	for _, addr := range redisCfg.Addrs {
+		nodeCfg := redisCfg
+		nodeCfg.Mode = RedisModeStandalone
+		nodeCfg.Addrs = []string{addr}
+		nodeStorage, err := NewRedisStorageFromConfig(nodeCfg)
+		if err != nil {
+			return nil, fmt.Errorf("nó %s: %w", addr, err)
+		}
+		nodes = append(nodes, ShardNode{Name: addr, Storage: nodeStorage})
+	}
+	return NewShardedStorage(nodes, opts), nil
+}
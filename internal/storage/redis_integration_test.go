@@ -43,41 +43,72 @@ func setupRedisContainer(t *testing.T) (*RedisRateLimiterStorage, func()) {
 }
 
 func TestRedis_Ping(t *testing.T) {
+	ctx := context.Background()
 	redisStorage, cleanup := setupRedisContainer(t)
 	defer cleanup()
 
-	err := redisStorage.Ping()
+	err := redisStorage.Ping(ctx)
 	assert.NoError(t, err, "O Redis deveria responder ao Ping() corretamente")
 }
 
 func TestRedis_IncrementRequest(t *testing.T) {
+	ctx := context.Background()
 	redisStorage, cleanup := setupRedisContainer(t)
 	defer cleanup()
 
 	key := "test_ip"
-	count, err := redisStorage.IncrementRequest(key)
+	count, err := redisStorage.IncrementRequest(ctx, key)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count, "A primeira requisição deve ter contagem 1")
 
-	count, err = redisStorage.IncrementRequest(key)
+	count, err = redisStorage.IncrementRequest(ctx, key)
 	assert.NoError(t, err)
 	assert.Equal(t, 2, count, "A segunda requisição deve ter contagem 2")
 }
 
 func TestRedis_BlockKey(t *testing.T) {
+	ctx := context.Background()
 	redisStorage, cleanup := setupRedisContainer(t)
 	defer cleanup()
 
 	key := "test_blocked_ip"
-	redisStorage.BlockKey(key, 2*time.Second)
+	redisStorage.BlockKey(ctx, key, 2*time.Second)
 
-	blocked, err := redisStorage.IsBlocked(key)
+	blocked, err := redisStorage.IsBlocked(ctx, key)
 	assert.NoError(t, err)
 	assert.True(t, blocked, "A chave deveria estar bloqueada")
 
 	time.Sleep(3 * time.Second)
 
-	blocked, err = redisStorage.IsBlocked(key)
+	blocked, err = redisStorage.IsBlocked(ctx, key)
 	assert.NoError(t, err)
 	assert.False(t, blocked, "A chave deve estar desbloqueada após o tempo de expiração")
 }
+
+func TestRedis_InspectAndUnblock(t *testing.T) {
+	ctx := context.Background()
+	redisStorage, cleanup := setupRedisContainer(t)
+	defer cleanup()
+
+	key := "test_inspect_ip"
+	redisStorage.IncrementRequest(ctx, key)
+	redisStorage.BlockKey(ctx, key, time.Minute)
+
+	keys, err := redisStorage.ListBlockedKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, keys)
+
+	info, err := redisStorage.InspectKey(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.Count)
+	assert.True(t, info.CountTTL > 0, "O contador deve ter TTL no Redis")
+	assert.True(t, info.Blocked)
+	assert.InDelta(t, time.Minute.Seconds(), info.BlockRemaining.Seconds(), 1)
+
+	err = redisStorage.UnblockKey(ctx, key)
+	assert.NoError(t, err)
+
+	blocked, err := redisStorage.IsBlocked(ctx, key)
+	assert.NoError(t, err)
+	assert.False(t, blocked, "A chave deve estar desbloqueada após o UnblockKey")
+}
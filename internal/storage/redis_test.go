@@ -42,6 +42,7 @@ func setupTestRedis(t *testing.T) (*RedisRateLimiterStorage, func()) {
 }
 
 func TestRedisRateLimiterStorage(t *testing.T) {
+	ctx := context.Background()
 	redisStorage, cleanup := setupTestRedis(t)
 	defer cleanup()
 
@@ -50,48 +51,48 @@ func TestRedisRateLimiterStorage(t *testing.T) {
 	tokenKey := "rate_limiter:token:abc123"
 
 	// Teste: IncrementRequest e GetRequestCount
-	count, err := redisStorage.IncrementRequest(ipKey)
+	count, err := redisStorage.IncrementRequest(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
 
-	count, err = redisStorage.GetRequestCount(ipKey)
+	count, err = redisStorage.GetRequestCount(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
 
 	// Teste: BlockKey e IsBlocked
-	err = redisStorage.BlockKey(ipKey, 5*time.Second)
+	err = redisStorage.BlockKey(ctx, ipKey, 5*time.Second)
 	assert.NoError(t, err)
 
-	blocked, err := redisStorage.IsBlocked(ipKey)
+	blocked, err := redisStorage.IsBlocked(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.True(t, blocked)
 
 	// Teste: SetBlockDuration e GetBlockDuration
-	err = redisStorage.SetBlockDuration(ipKey, 10*time.Second)
+	err = redisStorage.SetBlockDuration(ctx, ipKey, 10*time.Second)
 	assert.NoError(t, err)
 
-	blockDuration, err := redisStorage.GetBlockDuration(ipKey)
+	blockDuration, err := redisStorage.GetBlockDuration(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 10*time.Second, blockDuration)
 
 	// Teste: ResetKey
-	err = redisStorage.ResetKey(ipKey)
+	err = redisStorage.ResetKey(ctx, ipKey)
 	assert.NoError(t, err)
 
-	count, err = redisStorage.GetRequestCount(ipKey)
+	count, err = redisStorage.GetRequestCount(ctx, ipKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, count)
 
 	// Teste: IncrementRequest para um Token
-	count, err = redisStorage.IncrementRequest(tokenKey)
+	count, err = redisStorage.IncrementRequest(ctx, tokenKey)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, count)
 
 	// Teste: BlockKey para um Token
-	err = redisStorage.BlockKey(tokenKey, 3*time.Second)
+	err = redisStorage.BlockKey(ctx, tokenKey, 3*time.Second)
 	assert.NoError(t, err)
 
-	blocked, err = redisStorage.IsBlocked(tokenKey)
+	blocked, err = redisStorage.IsBlocked(ctx, tokenKey)
 	assert.NoError(t, err)
 	assert.True(t, blocked)
 }
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCertificate gera um certificado autoassinado e grava cert e chave em PEM
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis.internal"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func TestNewRedisTLSConfig(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+
+	tlsConfig, err := NewRedisTLSConfig(RedisTLSOptions{
+		CAFile:     certFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: "redis.internal",
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Equal(t, "redis.internal", tlsConfig.ServerName)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+}
+
+func TestNewRedisTLSConfig_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	certFile, _ := writeTestCertificate(t, dir)
+
+	_, err := NewRedisTLSConfig(RedisTLSOptions{CAFile: filepath.Join(dir, "inexistente.pem")})
+	assert.Error(t, err)
+
+	notPEM := filepath.Join(dir, "ca.txt")
+	os.WriteFile(notPEM, []byte("não é um certificado"), 0o600)
+	_, err = NewRedisTLSConfig(RedisTLSOptions{CAFile: notPEM})
+	assert.ErrorContains(t, err, "nenhum certificado")
+
+	_, err = NewRedisTLSConfig(RedisTLSOptions{CertFile: certFile})
+	assert.ErrorContains(t, err, "juntos")
+}
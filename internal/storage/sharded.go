@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardNode identifica um backend pelo nome (ex.: o endereço do Redis). O nome entra
+// no hash, então precisa ser estável entre instâncias e reinícios.
+type ShardNode struct {
+	Name    string
+	Storage RateLimiterStorage
+}
+
+type ShardedOptions struct {
+	// HealthInterval é o intervalo entre os Pings de cada nó feitos por Run.
+	HealthInterval time.Duration
+	// OnHealthChange é chamado quando um nó muda de saudável para indisponível ou vice-versa.
+	OnHealthChange func(node string, healthy bool)
+}
+
+type shardNode struct {
+	ShardNode
+	healthy atomic.Bool
+}
+
+// ShardedStorage distribui as chaves entre backends independentes por rendezvous
+// hashing: cada chave vai para o nó saudável de maior peso hash(nó, chave). Adicionar
+// ou remover um nó só move as chaves que eram (ou passam a ser) dele, e as chaves de
+// um nó indisponível vão para o próximo da lista daquela chave até ele voltar.
+type ShardedStorage struct {
+	nodes []*shardNode
+	opts  ShardedOptions
+}
+
+func NewShardedStorage(nodes []ShardNode, opts ShardedOptions) *ShardedStorage {
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = time.Second
+	}
+	s := &ShardedStorage{opts: opts}
+	for _, node := range nodes {
+		n := &shardNode{ShardNode: node}
+		n.healthy.Store(true)
+		s.nodes = append(s.nodes, n)
+	}
+	return s
+}
+
+// NodeFor devolve o nome do nó responsável pela chave no momento.
+func (s *ShardedStorage) NodeFor(key string) string {
+	return s.route(key).Name
+}
+
+// route escolhe o nó saudável de maior peso; se nenhum estiver saudável, usa o de
+// maior peso mesmo assim e o erro dele decide via modo de falha.
+func (s *ShardedStorage) route(key string) *shardNode {
+	var best, bestHealthy *shardNode
+	var bestScore, bestHealthyScore uint64
+	for _, node := range s.nodes {
+		score := rendezvousScore(node.Name, key)
+		if best == nil || score > bestScore {
+			best, bestScore = node, score
+		}
+		if node.healthy.Load() && (bestHealthy == nil || score > bestHealthyScore) {
+			bestHealthy, bestHealthyScore = node, score
+		}
+	}
+	if bestHealthy != nil {
+		return bestHealthy
+	}
+	return best
+}
+
+func rendezvousScore(node, key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(node))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	// FNV sozinho espalha mal chaves parecidas; o finalizador do splitmix64 corrige isso
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// CheckHealth faz Ping em todos os nós em paralelo e atualiza o roteamento.
+func (s *ShardedStorage) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, node := range s.nodes {
+		wg.Add(1)
+		go func(node *shardNode) {
+			defer wg.Done()
+			healthy := node.Storage.Ping(ctx) == nil
+			if node.healthy.Swap(healthy) != healthy && s.opts.OnHealthChange != nil {
+				s.opts.OnHealthChange(node.Name, healthy)
+			}
+		}(node)
+	}
+	wg.Wait()
+}
+
+// Run verifica a saúde dos nós a cada HealthInterval até o contexto ser cancelado.
+func (s *ShardedStorage) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.CheckHealth(ctx)
+		}
+	}
+}
+
+func (s *ShardedStorage) IncrementRequest(ctx context.Context, key string) (int, error) {
+	return s.route(key).Storage.IncrementRequest(ctx, key)
+}
+
+func (s *ShardedStorage) IncrementRequestBy(ctx context.Context, key string, delta int) (int, error) {
+	return s.route(key).Storage.IncrementRequestBy(ctx, key, delta)
+}
+
+func (s *ShardedStorage) GetRequestCount(ctx context.Context, key string) (int, error) {
+	return s.route(key).Storage.GetRequestCount(ctx, key)
+}
+
+func (s *ShardedStorage) BlockKey(ctx context.Context, key string, duration time.Duration) error {
+	return s.route(key).Storage.BlockKey(ctx, key, duration)
+}
+
+func (s *ShardedStorage) IsBlocked(ctx context.Context, key string) (bool, error) {
+	return s.route(key).Storage.IsBlocked(ctx, key)
+}
+
+func (s *ShardedStorage) GetBlockDuration(ctx context.Context, key string) (time.Duration, error) {
+	return s.route(key).Storage.GetBlockDuration(ctx, key)
+}
+
+func (s *ShardedStorage) GetBlockRemaining(ctx context.Context, key string) (time.Duration, error) {
+	return s.route(key).Storage.GetBlockRemaining(ctx, key)
+}
+
+func (s *ShardedStorage) IncrementStrikes(ctx context.Context, key string, decay time.Duration) (int, error) {
+	return s.route(key).Storage.IncrementStrikes(ctx, key, decay)
+}
+
+func (s *ShardedStorage) SetBlockDuration(ctx context.Context, key string, duration time.Duration) error {
+	return s.route(key).Storage.SetBlockDuration(ctx, key, duration)
+}
+
+func (s *ShardedStorage) InspectKey(ctx context.Context, key string) (KeyInfo, error) {
+	return s.route(key).Storage.InspectKey(ctx, key)
+}
+
+// ResetKey e UnblockKey são operações administrativas e valem para todos os nós
+// saudáveis: uma chave redirecionada durante uma queda pode ter estado em dois nós.
+func (s *ShardedStorage) ResetKey(ctx context.Context, key string) error {
+	return s.forEachHealthy(func(node RateLimiterStorage) error { return node.ResetKey(ctx, key) })
+}
+
+func (s *ShardedStorage) UnblockKey(ctx context.Context, key string) error {
+	return s.forEachHealthy(func(node RateLimiterStorage) error { return node.UnblockKey(ctx, key) })
+}
+
+func (s *ShardedStorage) ListBlockedKeys(ctx context.Context) ([]string, error) {
+	var mu sync.Mutex
+	seen := make(map[string]struct{})
+	err := s.forEachHealthy(func(node RateLimiterStorage) error {
+		keys, err := node.ListBlockedKeys(ctx)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		for _, key := range keys {
+			seen[key] = struct{}{}
+		}
+		mu.Unlock()
+		return nil
+	})
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, err
+}
+
+// Ping só falha se nenhum nó responder: com ao menos um nó as chaves são redirecionadas.
+func (s *ShardedStorage) Ping(ctx context.Context) error {
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, node := range s.nodes {
+		wg.Add(1)
+		go func(node *shardNode) {
+			defer wg.Done()
+			if err := node.Storage.Ping(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	if len(errs) == len(s.nodes) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// SubscribeInvalidations assina as invalidações de todos os nós que as suportam;
+// um desbloqueio publicado em qualquer nó chega a handler.
+func (s *ShardedStorage) SubscribeInvalidations(ctx context.Context, handler func(key string)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(s.nodes))
+	subscribed := 0
+	for _, node := range s.nodes {
+		subscriber, ok := node.Storage.(InvalidationSubscriber)
+		if !ok {
+			continue
+		}
+		subscribed++
+		go func() { errs <- subscriber.SubscribeInvalidations(ctx, handler) }()
+	}
+	if subscribed == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	// Encerra todas as assinaturas quando a primeira cai, para quem chamou reassinar
+	return <-errs
+}
+
+// forEachHealthy chama todos os nós saudáveis, ou todos os nós se nenhum estiver saudável.
+func (s *ShardedStorage) forEachHealthy(call func(node RateLimiterStorage) error) error {
+	var targets []*shardNode
+	for _, node := range s.nodes {
+		if node.healthy.Load() {
+			targets = append(targets, node)
+		}
+	}
+	if len(targets) == 0 {
+		targets = s.nodes
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, node := range targets {
+		wg.Add(1)
+		go func(node *shardNode) {
+			defer wg.Done()
+			if err := call(node.Storage); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
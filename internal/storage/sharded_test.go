@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// downableStorage simula um nó que pode cair e voltar
+type downableStorage struct {
+	*MemoryRateLimiterStorage
+	mu   sync.Mutex
+	down bool
+}
+
+func (d *downableStorage) setDown(down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.down = down
+}
+
+func (d *downableStorage) Ping(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func newShardNodes(names ...string) []ShardNode {
+	nodes := make([]ShardNode, 0, len(names))
+	for _, name := range names {
+		nodes = append(nodes, ShardNode{Name: name, Storage: &downableStorage{MemoryRateLimiterStorage: NewMemoryStorage()}})
+	}
+	return nodes
+}
+
+func shardKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("rate_limiter:ip:10.%d.%d.%d", i/65536, (i/256)%256, i%256)
+	}
+	return keys
+}
+
+func TestSharded_DistributesKeysEvenly(t *testing.T) {
+	sharded := NewShardedStorage(newShardNodes("redis-a:6379", "redis-b:6379", "redis-c:6379", "redis-d:6379"), ShardedOptions{})
+
+	counts := make(map[string]int)
+	for _, key := range shardKeys(20000) {
+		counts[sharded.NodeFor(key)]++
+	}
+
+	assert.Len(t, counts, 4)
+	for node, count := range counts {
+		assert.InDelta(t, 5000, count, 500, "Distribuição desigual no nó %s", node)
+	}
+}
+
+func TestSharded_AddingNodeMovesOnlyItsShare(t *testing.T) {
+	nodes := newShardNodes("redis-a:6379", "redis-b:6379", "redis-c:6379", "redis-d:6379")
+	before := NewShardedStorage(nodes, ShardedOptions{})
+	after := NewShardedStorage(append(nodes, newShardNodes("redis-e:6379")...), ShardedOptions{})
+
+	keys := shardKeys(20000)
+	moved := 0
+	for _, key := range keys {
+		from, to := before.NodeFor(key), after.NodeFor(key)
+		if from != to {
+			moved++
+			assert.Equal(t, "redis-e:6379", to, "Chaves só podem migrar para o nó novo")
+		}
+	}
+
+	// O ideal é 1/5 das chaves
+	assert.InDelta(t, 0.2, float64(moved)/float64(len(keys)), 0.03)
+}
+
+func TestSharded_RoutesAroundUnhealthyNode(t *testing.T) {
+	ctx := context.Background()
+	nodes := newShardNodes("redis-a:6379", "redis-b:6379", "redis-c:6379")
+	var changes []string
+	sharded := NewShardedStorage(nodes, ShardedOptions{
+		OnHealthChange: func(node string, healthy bool) {
+			changes = append(changes, fmt.Sprintf("%s=%v", node, healthy))
+		},
+	})
+
+	keys := shardKeys(3000)
+	original := make(map[string]string)
+	for _, key := range keys {
+		original[key] = sharded.NodeFor(key)
+	}
+
+	nodes[1].Storage.(*downableStorage).setDown(true)
+	sharded.CheckHealth(ctx)
+	assert.Equal(t, []string{"redis-b:6379=false"}, changes)
+
+	for _, key := range keys {
+		node := sharded.NodeFor(key)
+		assert.NotEqual(t, "redis-b:6379", node)
+		if original[key] != "redis-b:6379" {
+			assert.Equal(t, original[key], node, "Chaves de nós saudáveis não devem mudar de lugar")
+		}
+	}
+	assert.NoError(t, sharded.Ping(ctx), "Com nós restantes o storage continua disponível")
+
+	nodes[1].Storage.(*downableStorage).setDown(false)
+	sharded.CheckHealth(ctx)
+	for _, key := range keys {
+		assert.Equal(t, original[key], sharded.NodeFor(key))
+	}
+}
+
+func TestSharded_OperationsHitOwningNode(t *testing.T) {
+	ctx := context.Background()
+	nodes := newShardNodes("redis-a:6379", "redis-b:6379")
+	sharded := NewShardedStorage(nodes, ShardedOptions{})
+
+	count, err := sharded.IncrementRequest(ctx, "ip:1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, sharded.BlockKey(ctx, "ip:1", time.Minute))
+	assert.NoError(t, sharded.BlockKey(ctx, "ip:2", time.Minute))
+
+	owner := sharded.NodeFor("ip:1")
+	for _, node := range nodes {
+		blocked, _ := node.Storage.IsBlocked(ctx, "ip:1")
+		assert.Equal(t, node.Name == owner, blocked)
+	}
+
+	keys, err := sharded.ListBlockedKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ip:1", "ip:2"}, keys)
+
+	assert.NoError(t, sharded.UnblockKey(ctx, "ip:1"))
+	blocked, _ := sharded.IsBlocked(ctx, "ip:1")
+	assert.False(t, blocked)
+}
+
+func TestSharded_PingFailsOnlyWhenAllNodesAreDown(t *testing.T) {
+	ctx := context.Background()
+	nodes := newShardNodes("redis-a:6379", "redis-b:6379")
+	sharded := NewShardedStorage(nodes, ShardedOptions{})
+
+	nodes[0].Storage.(*downableStorage).setDown(true)
+	assert.NoError(t, sharded.Ping(ctx))
+
+	nodes[1].Storage.(*downableStorage).setDown(true)
+	assert.Error(t, sharded.Ping(ctx))
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const snapshotVersion = 1
+
+// memorySnapshot guarda bloqueios, janelas e violações com o instante absoluto de expiração, para
+// que o tempo em que o processo ficou parado também conte contra eles.
+// Snapshots antigos, sem counter_expiries, restauram os contadores com janela nova.
+type memorySnapshot struct {
+	Version          int                  `json:"version"`
+	TakenAt          time.Time            `json:"taken_at"`
+	Counters         map[string]int       `json:"counters"`
+	CounterExpiries  map[string]time.Time `json:"counter_expiries,omitempty"`
+	Blocks           map[string]time.Time `json:"blocks"`
+	BlockDurationsMs map[string]int64     `json:"block_durations_ms"`
+	Strikes          map[string]int       `json:"strikes,omitempty"`
+	StrikeExpiries   map[string]time.Time `json:"strike_expiries,omitempty"`
+}
+
+// WriteSnapshot serializa contadores, bloqueios ativos e durações em JSON.
+// Cada shard é copiado sob o próprio lock, sem parar os demais.
+func (m *MemoryRateLimiterStorage) WriteSnapshot(w io.Writer) error {
+	now := m.clock.Now()
+	snapshot := memorySnapshot{
+		Version:          snapshotVersion,
+		TakenAt:          now,
+		Counters:         make(map[string]int),
+		CounterExpiries:  make(map[string]time.Time),
+		Blocks:           make(map[string]time.Time),
+		BlockDurationsMs: make(map[string]int64),
+		Strikes:          make(map[string]int),
+		StrikeExpiries:   make(map[string]time.Time),
+	}
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		shard.purgeExpired(now)
+		for key, counter := range shard.requests {
+			snapshot.Counters[key] = counter.count
+			snapshot.CounterExpiries[key] = counter.expiresAt
+		}
+		for key, expiryTime := range shard.blocked {
+			snapshot.Blocks[key] = expiryTime
+		}
+		for key, duration := range shard.blockDurations {
+			snapshot.BlockDurationsMs[key] = duration.Milliseconds()
+		}
+		for key, strikes := range shard.strikes {
+			snapshot.Strikes[key] = strikes.count
+			snapshot.StrikeExpiries[key] = strikes.expiresAt
+		}
+		shard.mu.Unlock()
+	}
+
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// ReadSnapshot substitui o estado atual pelo do snapshot, descartando bloqueios que
+// expiraram enquanto o processo estava parado.
+func (m *MemoryRateLimiterStorage) ReadSnapshot(r io.Reader) error {
+	var snapshot memorySnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return err
+	}
+	if snapshot.Version != snapshotVersion {
+		return fmt.Errorf("versão de snapshot não suportada: %d", snapshot.Version)
+	}
+
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		shard.reset()
+		shard.mu.Unlock()
+	}
+
+	now := m.clock.Now()
+	for key, count := range snapshot.Counters {
+		expiryTime, exists := snapshot.CounterExpiries[key]
+		if !exists {
+			expiryTime = now.Add(counterWindow)
+		}
+		if !expiryTime.After(now) {
+			continue
+		}
+		shard, _ := m.lock(key)
+		shard.requests[key] = memoryCounter{count: count, expiresAt: expiryTime}
+		shard.schedule(key, expiryCounter, expiryTime)
+		shard.track(key, true)
+		shard.mu.Unlock()
+	}
+	for key, count := range snapshot.Strikes {
+		expiryTime := snapshot.StrikeExpiries[key]
+		if !expiryTime.After(now) {
+			continue
+		}
+		shard, _ := m.lock(key)
+		shard.strikes[key] = memoryCounter{count: count, expiresAt: expiryTime}
+		shard.schedule(key, expiryStrikes, expiryTime)
+		shard.track(key, true)
+		shard.mu.Unlock()
+	}
+	// A duração só volta junto com um bloqueio ainda ativo, que a expira ao vencer
+	for key, expiryTime := range snapshot.Blocks {
+		if !expiryTime.After(now) {
+			continue
+		}
+		shard, _ := m.lock(key)
+		shard.blocked[key] = expiryTime
+		if durationMs, exists := snapshot.BlockDurationsMs[key]; exists {
+			shard.blockDurations[key] = time.Duration(durationMs) * time.Millisecond
+		}
+		shard.schedule(key, expiryBlock, expiryTime)
+		shard.track(key, true)
+		shard.mu.Unlock()
+	}
+	return nil
+}
+
+// SaveSnapshot grava o snapshot em um arquivo temporário e o renomeia sobre path,
+// para que uma queda no meio da escrita nunca deixe um snapshot truncado.
+func (m *MemoryRateLimiterStorage) SaveSnapshot(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := m.WriteSnapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot restaura o estado salvo em path. Um arquivo inexistente devolve um
+// erro que satisfaz errors.Is(err, os.ErrNotExist).
+func (m *MemoryRateLimiterStorage) LoadSnapshot(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return m.ReadSnapshot(file)
+}
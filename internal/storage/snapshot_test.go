@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySnapshot_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	original := NewMemoryStorage()
+	_, _ = original.IncrementRequestBy(ctx, "ip:1", 3)
+	assert.NoError(t, original.BlockKey(ctx, "ip:1", time.Minute))
+	assert.NoError(t, original.BlockKey(ctx, "token:abc", time.Minute))
+	assert.NoError(t, original.SetBlockDuration(ctx, "token:abc", 5*time.Minute))
+	_, _ = original.IncrementStrikes(ctx, "ip:1", time.Hour)
+	_, _ = original.IncrementStrikes(ctx, "ip:1", time.Hour)
+
+	var buf bytes.Buffer
+	assert.NoError(t, original.WriteSnapshot(&buf))
+
+	restored := NewMemoryStorage()
+	assert.NoError(t, restored.ReadSnapshot(&buf))
+
+	count, _ := restored.GetRequestCount(ctx, "ip:1")
+	assert.Equal(t, 3, count)
+	blocked, _ := restored.IsBlocked(ctx, "ip:1")
+	assert.True(t, blocked)
+	duration, _ := restored.GetBlockDuration(ctx, "token:abc")
+	assert.Equal(t, 5*time.Minute, duration)
+	strikes, _ := restored.IncrementStrikes(ctx, "ip:1", time.Hour)
+	assert.Equal(t, 3, strikes, "Reincidências devem sobreviver ao reinício")
+
+	// O tempo restante é preservado, não reiniciado
+	info, _ := restored.InspectKey(ctx, "ip:1")
+	assert.True(t, info.BlockRemaining > 0 && info.BlockRemaining <= time.Minute)
+}
+
+func TestMemorySnapshot_DropsBlocksExpiredWhileStopped(t *testing.T) {
+	ctx := context.Background()
+	clock := newManualClock()
+	original := NewMemoryStorageWithOptions(MemoryOptions{Clock: clock})
+	assert.NoError(t, original.BlockKey(ctx, "ip:1", 30*time.Millisecond))
+
+	var buf bytes.Buffer
+	assert.NoError(t, original.WriteSnapshot(&buf))
+	clock.Advance(50 * time.Millisecond)
+
+	restored := NewMemoryStorageWithOptions(MemoryOptions{Clock: clock})
+	assert.NoError(t, restored.ReadSnapshot(&buf))
+
+	keys, _ := restored.ListBlockedKeys(ctx)
+	assert.Empty(t, keys)
+
+	// A duração do bloqueio vencido também não volta, nem vai para o próximo snapshot
+	duration, _ := restored.GetBlockDuration(ctx, "ip:1")
+	assert.Zero(t, duration)
+	for _, shard := range restored.shards {
+		assert.Empty(t, shard.blockDurations)
+	}
+}
+
+func TestMemorySnapshot_SaveAndLoadFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "snapshots", "memory.json")
+
+	original := NewMemoryStorage()
+	assert.NoError(t, original.BlockKey(ctx, "ip:1", time.Minute))
+	assert.NoError(t, original.SaveSnapshot(path))
+
+	restored := NewMemoryStorage()
+	assert.NoError(t, restored.LoadSnapshot(path))
+	blocked, _ := restored.IsBlocked(ctx, "ip:1")
+	assert.True(t, blocked)
+
+	// Nenhum arquivo temporário deve sobrar ao lado do snapshot
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1)
+}
+
+func TestMemorySnapshot_MissingFileAndBadVersion(t *testing.T) {
+	memStorage := NewMemoryStorage()
+
+	err := memStorage.LoadSnapshot(filepath.Join(t.TempDir(), "inexistente.json"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	err = memStorage.ReadSnapshot(strings.NewReader(`{"version": 99}`))
+	assert.ErrorContains(t, err, "versão")
+}
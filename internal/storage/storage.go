@@ -1,13 +1,36 @@
 package storage
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type RateLimiterStorage interface {
-	IncrementRequest(key string) (int, error)
-	GetRequestCount(key string) (int, error)
-	BlockKey(key string, duration time.Duration) error
-	IsBlocked(key string) (bool, error)
-	ResetKey(key string) error
-	GetBlockDuration(key string) (time.Duration, error)
-	SetBlockDuration(key string, duration time.Duration) error
+	IncrementRequest(ctx context.Context, key string) (int, error)
+	IncrementRequestBy(ctx context.Context, key string, delta int) (int, error)
+	GetRequestCount(ctx context.Context, key string) (int, error)
+	BlockKey(ctx context.Context, key string, duration time.Duration) error
+	IsBlocked(ctx context.Context, key string) (bool, error)
+	ResetKey(ctx context.Context, key string) error
+	GetBlockDuration(ctx context.Context, key string) (time.Duration, error)
+	// GetBlockRemaining devolve quanto falta para o bloqueio expirar (zero se a chave está livre).
+	GetBlockRemaining(ctx context.Context, key string) (time.Duration, error)
+	SetBlockDuration(ctx context.Context, key string, duration time.Duration) error
+	// IncrementStrikes registra mais uma violação da chave e devolve o total. As violações
+	// são esquecidas quando passa decay sem nenhuma nova.
+	IncrementStrikes(ctx context.Context, key string, decay time.Duration) (int, error)
+	UnblockKey(ctx context.Context, key string) error
+	ListBlockedKeys(ctx context.Context) ([]string, error)
+	InspectKey(ctx context.Context, key string) (KeyInfo, error)
+	Ping(ctx context.Context) error
+}
+
+// KeyInfo é a fotografia do estado de uma chave usada pela API administrativa.
+// CountTTL zero indica que o contador não expira.
+type KeyInfo struct {
+	Key            string
+	Count          int
+	CountTTL       time.Duration
+	Blocked        bool
+	BlockRemaining time.Duration
 }
@@ -0,0 +1,417 @@
+// Package storagetest reúne o contrato que todo storage.RateLimiterStorage precisa
+// cumprir. Cada backend roda a mesma suíte, controlando o tempo pelo Harness.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"rate-limiter/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counterWindow é a janela fixa dos contadores, a mesma em todos os backends.
+const counterWindow = time.Minute
+
+// Clock é um relógio manual, que só anda quando Advance é chamado. Começa em um
+// minuto cheio para que backends de janela alinhada e de janela iniciada no primeiro
+// incremento vejam as mesmas viradas.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewClock() *Clock {
+	return &Clock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Harness é um storage novo e a forma de fazer o tempo dele andar.
+type Harness struct {
+	Storage storage.RateLimiterStorage
+	Advance func(d time.Duration)
+}
+
+// Run executa a suíte inteira; newHarness é chamado uma vez por subteste, para que
+// nenhum caso dependa do estado deixado por outro.
+func Run(t *testing.T, newHarness func(t *testing.T) Harness) {
+	cases := []struct {
+		name string
+		test func(t *testing.T, h Harness)
+	}{
+		{"Counting", testCounting},
+		{"CounterWindow", testCounterWindow},
+		{"Reset", testReset},
+		{"Block", testBlock},
+		{"Reblock", testReblock},
+		{"SetBlockDuration", testSetBlockDuration},
+		{"Unblock", testUnblock},
+		{"Strikes", testStrikes},
+		{"ListBlockedKeys", testListBlockedKeys},
+		{"Concurrency", testConcurrency},
+		{"Ping", testPing},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newHarness(t))
+		})
+	}
+}
+
+func testCounting(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	count, err := h.Storage.GetRequestCount(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "Chave desconhecida deve começar zerada")
+
+	for want := 1; want <= 2; want++ {
+		count, err = h.Storage.IncrementRequest(ctx, "ip:1")
+		require.NoError(t, err)
+		assert.Equal(t, want, count)
+	}
+	count, err = h.Storage.IncrementRequestBy(ctx, "ip:1", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	count, err = h.Storage.GetRequestCount(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	// Chaves são independentes
+	count, err = h.Storage.GetRequestCount(ctx, "ip:2")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func testCounterWindow(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	_, err := h.Storage.IncrementRequest(ctx, "ip:1")
+	require.NoError(t, err)
+	h.Advance(counterWindow / 2)
+
+	// Incrementos dentro da janela não a estendem
+	count, err := h.Storage.IncrementRequest(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	info, err := h.Storage.InspectKey(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Count)
+	assert.InDelta(t, (counterWindow / 2).Seconds(), info.CountTTL.Seconds(), 1)
+
+	h.Advance(counterWindow/2 + time.Second)
+
+	count, err = h.Storage.GetRequestCount(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count, "Contador deve zerar quando a janela acaba")
+
+	count, err = h.Storage.IncrementRequest(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "Incremento após a janela abre uma janela nova")
+}
+
+func testReset(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	_, err := h.Storage.IncrementRequestBy(ctx, "ip:1", 4)
+	require.NoError(t, err)
+	require.NoError(t, h.Storage.BlockKey(ctx, "ip:1", time.Minute))
+
+	require.NoError(t, h.Storage.ResetKey(ctx, "ip:1"))
+
+	count, err := h.Storage.GetRequestCount(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// ResetKey zera apenas o contador
+	blocked, err := h.Storage.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	count, err = h.Storage.IncrementRequest(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testBlock(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	blocked, err := h.Storage.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	require.NoError(t, h.Storage.BlockKey(ctx, "ip:1", 2*time.Minute))
+
+	blocked, err = h.Storage.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	duration, err := h.Storage.GetBlockDuration(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, duration, "GetBlockDuration deve devolver a duração aplicada")
+
+	remaining, err := h.Storage.GetBlockRemaining(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.InDelta(t, (2 * time.Minute).Seconds(), remaining.Seconds(), 1)
+
+	info, err := h.Storage.InspectKey(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.True(t, info.Blocked)
+	assert.InDelta(t, (2 * time.Minute).Seconds(), info.BlockRemaining.Seconds(), 1)
+
+	keys, err := h.Storage.ListBlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:1"}, keys)
+
+	h.Advance(time.Minute)
+
+	blocked, err = h.Storage.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	// O tempo restante diminui; a duração registrada continua a original
+	remaining, err = h.Storage.GetBlockRemaining(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), remaining.Seconds(), 1)
+
+	duration, err = h.Storage.GetBlockDuration(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, duration)
+
+	info, err = h.Storage.InspectKey(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), info.BlockRemaining.Seconds(), 1)
+
+	h.Advance(time.Minute + time.Second)
+
+	blocked, err = h.Storage.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.False(t, blocked, "Bloqueio deve expirar sozinho")
+
+	remaining, err = h.Storage.GetBlockRemaining(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+
+	duration, err = h.Storage.GetBlockDuration(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, duration, "A duração registrada expira junto com o bloqueio")
+
+	info, err = h.Storage.InspectKey(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.False(t, info.Blocked)
+	assert.Zero(t, info.BlockRemaining)
+
+	keys, err = h.Storage.ListBlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func testReblock(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	require.NoError(t, h.Storage.BlockKey(ctx, "ip:1", time.Minute))
+	h.Advance(30 * time.Second)
+	require.NoError(t, h.Storage.BlockKey(ctx, "ip:1", 5*time.Minute))
+
+	duration, err := h.Storage.GetBlockDuration(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, duration)
+
+	// O bloqueio novo substitui o antigo, inclusive a expiração
+	h.Advance(time.Minute)
+
+	blocked, err := h.Storage.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	info, err := h.Storage.InspectKey(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.InDelta(t, (4 * time.Minute).Seconds(), info.BlockRemaining.Seconds(), 1)
+}
+
+func testSetBlockDuration(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	require.NoError(t, h.Storage.BlockKey(ctx, "ip:1", time.Minute))
+	require.NoError(t, h.Storage.SetBlockDuration(ctx, "ip:1", 10*time.Minute))
+
+	duration, err := h.Storage.GetBlockDuration(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, duration)
+
+	// Trocar a duração registrada não estende o bloqueio em curso
+	remaining, err := h.Storage.GetBlockRemaining(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Seconds(), remaining.Seconds(), 1)
+
+	h.Advance(time.Minute + time.Second)
+
+	blocked, err := h.Storage.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	// Sem bloqueio ativo a duração não fica guardada para sempre
+	require.NoError(t, h.Storage.SetBlockDuration(ctx, "ip:2", 10*time.Minute))
+	h.Advance(time.Hour)
+
+	blocked, err = h.Storage.IsBlocked(ctx, "ip:2")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+	duration, err = h.Storage.GetBlockDuration(ctx, "ip:2")
+	require.NoError(t, err)
+	assert.Zero(t, duration)
+}
+
+func testUnblock(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	_, err := h.Storage.IncrementRequestBy(ctx, "ip:1", 3)
+	require.NoError(t, err)
+	require.NoError(t, h.Storage.BlockKey(ctx, "ip:1", time.Minute))
+
+	require.NoError(t, h.Storage.UnblockKey(ctx, "ip:1"))
+
+	blocked, err := h.Storage.IsBlocked(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.False(t, blocked)
+
+	duration, err := h.Storage.GetBlockDuration(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, duration)
+
+	remaining, err := h.Storage.GetBlockRemaining(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+
+	keys, err := h.Storage.ListBlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// UnblockKey não mexe no contador
+	count, err := h.Storage.GetRequestCount(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// Desbloquear uma chave livre não é erro
+	assert.NoError(t, h.Storage.UnblockKey(ctx, "ip:2"))
+}
+
+func testStrikes(t *testing.T, h Harness) {
+	ctx := context.Background()
+	const decay = 10 * time.Minute
+
+	for want := 1; want <= 2; want++ {
+		strikes, err := h.Storage.IncrementStrikes(ctx, "ip:1", decay)
+		require.NoError(t, err)
+		assert.Equal(t, want, strikes)
+	}
+
+	// Cada violação adia o esquecimento: 18 minutos depois da primeira ainda conta
+	h.Advance(9 * time.Minute)
+	strikes, err := h.Storage.IncrementStrikes(ctx, "ip:1", decay)
+	require.NoError(t, err)
+	assert.Equal(t, 3, strikes)
+
+	h.Advance(9 * time.Minute)
+	strikes, err = h.Storage.IncrementStrikes(ctx, "ip:1", decay)
+	require.NoError(t, err)
+	assert.Equal(t, 4, strikes)
+
+	// Violações são independentes do contador de requisições
+	require.NoError(t, h.Storage.ResetKey(ctx, "ip:1"))
+	strikes, err = h.Storage.IncrementStrikes(ctx, "ip:2", decay)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strikes)
+
+	// Um período inteiro sem violações zera o histórico
+	h.Advance(decay + time.Second)
+	strikes, err = h.Storage.IncrementStrikes(ctx, "ip:1", decay)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strikes)
+}
+
+func testListBlockedKeys(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	keys, err := h.Storage.ListBlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	require.NoError(t, h.Storage.BlockKey(ctx, "token:c", time.Minute))
+	require.NoError(t, h.Storage.BlockKey(ctx, "ip:a", 3*time.Minute))
+	require.NoError(t, h.Storage.BlockKey(ctx, "ip:b", time.Minute))
+	_, err = h.Storage.IncrementRequest(ctx, "ip:livre")
+	require.NoError(t, err)
+
+	keys, err = h.Storage.ListBlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:a", "ip:b", "token:c"}, keys, "Listagem deve vir ordenada")
+
+	h.Advance(2 * time.Minute)
+
+	keys, err = h.Storage.ListBlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ip:a"}, keys)
+}
+
+func testConcurrency(t *testing.T, h Harness) {
+	ctx := context.Background()
+	const workers, perWorker = 8, 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker*3)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := h.Storage.IncrementRequest(ctx, "ip:1"); err != nil {
+					errs <- err
+				}
+				// Bloqueios e leituras de outras chaves no meio não podem atrapalhar a contagem
+				key := fmt.Sprintf("ip:%d", w+2)
+				if err := h.Storage.BlockKey(ctx, key, time.Minute); err != nil {
+					errs <- err
+				}
+				if _, err := h.Storage.IsBlocked(ctx, key); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	count, err := h.Storage.GetRequestCount(ctx, "ip:1")
+	require.NoError(t, err)
+	assert.Equal(t, workers*perWorker, count)
+
+	keys, err := h.Storage.ListBlockedKeys(ctx)
+	require.NoError(t, err)
+	assert.Len(t, keys, workers)
+}
+
+func testPing(t *testing.T, h Harness) {
+	assert.NoError(t, h.Storage.Ping(context.Background()))
+}
@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetup_Exporters(t *testing.T) {
+	for _, exporter := range []string{"", ExporterNone, ExporterStdout} {
+		shutdown, err := Setup(context.Background(), exporter, "rate-limiter-test")
+		assert.NoError(t, err, "exporter %q deveria ser aceito", exporter)
+		assert.NoError(t, shutdown(context.Background()))
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), "zipkin", "rate-limiter-test")
+	assert.Error(t, err)
+}